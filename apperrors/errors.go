@@ -0,0 +1,111 @@
+package apperrors
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "gorm.io/gorm"
+)
+
+// Machine-readable error codes returned in the API error envelope
+const (
+    CodeBadRequest       = "BAD_REQUEST"
+    CodeValidationFailed = "VALIDATION_FAILED"
+    CodeUnauthorized     = "UNAUTHORIZED"
+    CodeForbidden        = "FORBIDDEN"
+    CodeNotFound         = "NOT_FOUND"
+    CodeConflict         = "CONFLICT"
+    CodeGone             = "GONE"
+    CodeInternal         = "INTERNAL_ERROR"
+    CodeUpstream         = "UPSTREAM_ERROR"
+)
+
+// Error is an application error that knows how it should be presented to API clients
+type Error struct {
+    Status  int         // HTTP status code
+    Code    string      // Machine-readable error code
+    Message string      // Human-readable message safe to show to clients
+    Details interface{} // Optional structured details, e.g. failing fields
+    Err     error       // Underlying cause, logged but never rendered
+}
+
+func (e *Error) Error() string {
+    if e.Err != nil {
+        return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+    }
+    return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+    return e.Err
+}
+
+// WithDetails returns a copy of the error carrying the given details
+func (e *Error) WithDetails(details interface{}) *Error {
+    clone := *e
+    clone.Details = details
+    return &clone
+}
+
+// New creates an application error with the given status, code and message
+func New(status int, code, message string) *Error {
+    return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest reports a malformed or semantically invalid request
+func BadRequest(message string) *Error {
+    return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Validation reports field-level validation failures
+func Validation(details interface{}) *Error {
+    return New(http.StatusBadRequest, CodeValidationFailed, "Validation failed").WithDetails(details)
+}
+
+// Unauthorized reports missing or invalid credentials
+func Unauthorized(message string) *Error {
+    return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden reports an authenticated request that is not allowed
+func Forbidden(message string) *Error {
+    return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound reports that the named resource does not exist
+func NotFound(resource string) *Error {
+    return New(http.StatusNotFound, CodeNotFound, resource+" not found")
+}
+
+// Conflict reports a request that clashes with existing state
+func Conflict(message string) *Error {
+    return New(http.StatusConflict, CodeConflict, message)
+}
+
+// Gone reports a resource that existed but can no longer be retrieved
+func Gone(message string) *Error {
+    return New(http.StatusGone, CodeGone, message)
+}
+
+// Internal reports an unexpected server-side failure
+func Internal(message string, err error) *Error {
+    e := New(http.StatusInternalServerError, CodeInternal, message)
+    e.Err = err
+    return e
+}
+
+// Upstream reports a failure in an external service we depend on
+func Upstream(message string, err error) *Error {
+    e := New(http.StatusBadGateway, CodeUpstream, message)
+    e.Err = err
+    return e
+}
+
+// FromDB maps a database error to a not-found or internal error for the named resource
+func FromDB(err error, resource string) *Error {
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return NotFound(resource)
+    }
+    return Internal("Error retrieving "+strings.ToLower(resource), err)
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+    "shei-deli/config"
+    "shei-deli/controllers"
+    "shei-deli/fixtures"
+    "shei-deli/migrations"
+    "shei-deli/models"
+    "shei-deli/ranking"
+    "shei-deli/repository"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// runMigrate applies pending migrations (up, the default), reverts the latest
+// ones (down [steps]), lists them (status) or writes a new one (create <name>)
+func runMigrate(cfg config.Config, args []string) error {
+    command := "up"
+    if len(args) > 0 {
+        command, args = args[0], args[1:]
+    }
+
+    if command == "create" {
+        if len(args) != 1 {
+            return usageError("migrate create <name>")
+        }
+        path, err := migrations.Create("migrations", args[0], time.Now())
+        if err != nil {
+            return fmt.Errorf("failed to create migration: %w", err)
+        }
+        log.Printf("Created %s", path)
+        return nil
+    }
+
+    db, err := config.OpenDatabase(cfg.Database)
+    if err != nil {
+        return fmt.Errorf("failed to connect to database: %w", err)
+    }
+    defer config.CloseDatabase(db)
+    switch command {
+    case "up":
+        applied, err := migrations.Up(db)
+        for _, m := range applied {
+            log.Printf("Applied migration %d_%s", m.Version, m.Name)
+        }
+        if err != nil {
+            return fmt.Errorf("failed to migrate database: %w", err)
+        }
+        if len(applied) == 0 {
+            log.Println("Database is up to date")
+        }
+    case "down":
+        steps := 1
+        if len(args) > 0 {
+            parsed, err := strconv.Atoi(args[0])
+            if err != nil || parsed < 1 {
+                return usageError("migrate down [steps]")
+            }
+            steps = parsed
+        }
+        reverted, err := migrations.Down(db, steps)
+        for _, m := range reverted {
+            log.Printf("Reverted migration %d_%s", m.Version, m.Name)
+        }
+        if err != nil {
+            return fmt.Errorf("failed to revert migrations: %w", err)
+        }
+    case "status":
+        statuses, err := migrations.StatusOf(db)
+        if err != nil {
+            return fmt.Errorf("failed to read migration status: %w", err)
+        }
+        for _, status := range statuses {
+            state := "pending"
+            if status.AppliedAt != nil {
+                state = "applied " + status.AppliedAt.Format(time.RFC3339)
+            }
+            if status.Unknown {
+                state += " (not in this build)"
+            }
+            fmt.Printf("%d  %-30s  %s\n", status.Version, status.Name, state)
+        }
+    default:
+        return fmt.Errorf("unknown migrate command %q (want up, down, status or create)", command)
+    }
+    return nil
+}
+
+// runSeed creates the admin account and loads a fixture set, updating rows it
+// loaded before to match. --recipes imports an export instead of the fixtures;
+// --reset rebuilds the schema first.
+func runSeed(cfg config.Config, args []string) error {
+    const usage = "seed [--reset] [--fixtures demo|test|empty|file] [--recipes file]"
+    fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+    reset := fs.Bool("reset", false, "drop every table and migrate from scratch first")
+    fs.StringVar(&cfg.Seed.Fixtures, "fixtures", cfg.Seed.Fixtures, "fixture set or file to load")
+    recipesFile := fs.String("recipes", "", "import recipes from this export instead of the fixtures")
+    if rest, err := parseArgs(fs, args); err != nil {
+        return err
+    } else if len(rest) > 0 {
+        return usageError(usage)
+    }
+
+    // Read the files before touching the database so a typo can't leave it half reset
+    var records []models.RecipeRecord
+    if *recipesFile != "" {
+        var err error
+        if records, err = readRecipes(*recipesFile); err != nil {
+            return err
+        }
+    } else if _, err := fixtures.Load(cfg.Seed.Fixtures); err != nil {
+        return fmt.Errorf("failed to load fixtures: %w", err)
+    }
+
+    open := config.InitDatabase
+    if *reset {
+        open = config.ResetDatabase
+    }
+    db, err := open(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+    cfg.Seed.Admin = true
+    cfg.Seed.SampleRecipes = *recipesFile == ""
+    config.ReseedDatabase(db, cfg)
+
+    if *recipesFile != "" {
+        result, err := repository.NewRecipeRepository(db).Import(records, cfg.Admin.Username)
+        if err != nil {
+            return fmt.Errorf("failed to import recipes: %w", err)
+        }
+        log.Printf("Imported recipes: %d created, %d already present", result.Created, result.Skipped)
+    }
+    return nil
+}
+
+// runUser creates, promotes or deactivates an account
+func runUser(cfg config.Config, args []string) error {
+    const usage = "user create --username name --email address [--password secret] [--role role] | user promote <username> [--role role] | user deactivate <username>"
+    if len(args) == 0 {
+        return usageError(usage)
+    }
+    subcommand, args := args[0], args[1:]
+
+    fs := flag.NewFlagSet("user "+subcommand, flag.ContinueOnError)
+    var username, email, password string
+    var role string
+    switch subcommand {
+    case "create":
+        fs.StringVar(&username, "username", "", "username")
+        fs.StringVar(&email, "email", "", "email address")
+        fs.StringVar(&password, "password", "", "password; one is generated when empty")
+        fs.StringVar(&role, "role", models.RoleUser, "user, moderator or admin")
+    case "promote":
+        fs.StringVar(&role, "role", models.RoleModerator, "moderator or admin")
+    case "deactivate":
+    default:
+        return usageError(usage)
+    }
+    rest, err := parseArgs(fs, args)
+    if err != nil {
+        return err
+    }
+    if subcommand == "create" {
+        if len(rest) > 0 || username == "" || email == "" {
+            return usageError(usage)
+        }
+        if fields := controllers.ValidateAccountIdentity(username, email); len(fields) > 0 {
+            return fieldsError(fields)
+        }
+    } else if len(rest) != 1 {
+        return usageError(usage)
+    } else {
+        username = rest[0]
+    }
+    if role != "" && role != models.RoleUser && role != models.RoleModerator && role != models.RoleAdmin {
+        return fmt.Errorf("unknown role %q (want user, moderator or admin)", role)
+    }
+
+    db, err := config.InitDatabase(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+    users := repository.NewUserRepository(db)
+    if subcommand == "create" {
+        return createUser(users, username, email, password, role)
+    }
+
+    user, err := users.FindByLogin(username)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            return fmt.Errorf("no user named %q", username)
+        }
+        return err
+    }
+    if subcommand == "promote" {
+        if err := users.SetRole(&user, role); err != nil {
+            return fmt.Errorf("failed to promote user: %w", err)
+        }
+        log.Printf("User %q now has the %s role", user.Username, role)
+        return nil
+    }
+    if err := users.SetActive(&user, false); err != nil {
+        return fmt.Errorf("failed to deactivate user: %w", err)
+    }
+    log.Printf("Deactivated user %q", user.Username)
+    return nil
+}
+
+// createUser adds an active account, generating a password when none is given
+func createUser(users repository.UserRepository, username, email, password, role string) error {
+    generated := password == ""
+    if generated {
+        var err error
+        if password, err = config.RandomPassword(); err != nil {
+            return err
+        }
+    } else if !models.StrongPassword(password) {
+        return errors.New("password must be 8 to 72 characters with a letter and a number")
+    }
+
+    taken, err := users.Taken(username, email)
+    if err != nil {
+        return err
+    }
+    if taken {
+        return errors.New("username or email already exists")
+    }
+
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return err
+    }
+    user := models.User{
+        Username: username,
+        Email:    email,
+        Password: string(hashedPassword),
+        IsActive: true,
+        Role:     role,
+    }
+    if err := users.Create(&user); err != nil {
+        return fmt.Errorf("failed to create user: %w", err)
+    }
+    log.Printf("Created %s %q", role, username)
+    if generated {
+        fmt.Printf("Generated password for %q: %s\n", username, password)
+    }
+    return nil
+}
+
+// fieldsError reports the fields of a command's arguments that failed validation
+func fieldsError(fields []controllers.FieldError) error {
+    messages := make([]string, len(fields))
+    for i, f := range fields {
+        messages[i] = f.Field + " " + f.Message
+    }
+    return errors.New(strings.Join(messages, "; "))
+}
+
+// runRecipe exports approved recipes as JSON or imports such an export
+func runRecipe(cfg config.Config, args []string) error {
+    const usage = "recipe export [--output file] [--author username] [--category key] | recipe import <file> [--author username]"
+    if len(args) == 0 {
+        return usageError(usage)
+    }
+    subcommand, args := args[0], args[1:]
+
+    fs := flag.NewFlagSet("recipe "+subcommand, flag.ContinueOnError)
+    var output, author, category string
+    switch subcommand {
+    case "export":
+        fs.StringVar(&output, "output", "", "file to write; standard output when empty")
+        fs.StringVar(&author, "author", "", "only recipes by this username")
+        fs.StringVar(&category, "category", "", "only recipes in this category")
+    case "import":
+        fs.StringVar(&author, "author", cfg.Admin.Username, "username credited for records without an author")
+    default:
+        return usageError(usage)
+    }
+    rest, err := parseArgs(fs, args)
+    if err != nil {
+        return err
+    }
+
+    if subcommand == "import" {
+        if len(rest) != 1 {
+            return usageError(usage)
+        }
+        records, err := readRecipes(rest[0])
+        if err != nil {
+            return err
+        }
+        db, err := config.InitDatabase(cfg.Database)
+        if err != nil {
+            return err
+        }
+        defer config.CloseDatabase(db)
+        result, err := repository.NewRecipeRepository(db).Import(records, author)
+        if err != nil {
+            return fmt.Errorf("failed to import recipes: %w", err)
+        }
+        log.Printf("Imported recipes: %d created, %d already present", result.Created, result.Skipped)
+        return nil
+    }
+
+    if len(rest) > 0 {
+        return usageError(usage)
+    }
+    db, err := config.InitDatabase(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+    repos := repository.New(db)
+    var filter repository.RecipeFilter
+    if author != "" {
+        user, err := repos.Users.FindByLogin(author)
+        if errors.Is(err, repository.ErrNotFound) {
+            return fmt.Errorf("no user named %q", author)
+        }
+        if err != nil {
+            return err
+        }
+        filter.UserID = user.ID
+    }
+    if category != "" {
+        filter.Categories = []string{category}
+    }
+    records, err := repos.Recipes.Export(filter)
+    if err != nil {
+        return fmt.Errorf("failed to export recipes: %w", err)
+    }
+    data, err := json.MarshalIndent(records, "", "  ")
+    if err != nil {
+        return err
+    }
+    data = append(data, '\n')
+    if output == "" {
+        _, err = os.Stdout.Write(data)
+        return err
+    }
+    if err := os.WriteFile(output, data, 0644); err != nil {
+        return err
+    }
+    log.Printf("Exported %d recipes to %s", len(records), output)
+    return nil
+}
+
+// readRecipes loads a file written by `recipe export`
+func readRecipes(path string) ([]models.RecipeRecord, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var records []models.RecipeRecord
+    if err := json.Unmarshal(data, &records); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return records, nil
+}
+
+// runRatings repairs drifted rating aggregates and refreshes trending scores
+func runRatings(cfg config.Config, args []string) error {
+    if len(args) != 1 || args[0] != "recompute" {
+        return usageError("ratings recompute")
+    }
+    db, err := config.InitDatabase(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+    fixed, err := models.RecomputeRatingAggregates(db)
+    if err != nil {
+        return fmt.Errorf("failed to reconcile rating aggregates: %w", err)
+    }
+    log.Printf("Reconciled rating aggregates: %d recipes updated", fixed)
+    if err := ranking.RefreshTrending(db, time.Now()); err != nil {
+        return fmt.Errorf("failed to refresh trending scores: %w", err)
+    }
+    log.Println("Refreshed trending scores")
+    return nil
+}
+
+// runMedia moves uploads from static/uploads into the media store
+func runMedia(cfg config.Config, args []string) error {
+    if len(args) != 1 || args[0] != "migrate" {
+        return usageError("media migrate")
+    }
+    db, err := config.InitDatabase(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+    store, err := configureMedia(cfg.Media)
+    if err != nil {
+        return err
+    }
+    migrated, err := models.MigrateLegacyMedia(db, store, filepath.Join(cfg.Server.StaticDir, "uploads"))
+    if err != nil {
+        return fmt.Errorf("failed to migrate media: %w", err)
+    }
+    log.Printf("Migrated media: %d URLs rewritten", migrated)
+    return nil
+}
+
+// runTrash permanently removes trash older than the retention window
+func runTrash(cfg config.Config, args []string) error {
+    if len(args) != 1 || args[0] != "purge" {
+        return usageError("trash purge")
+    }
+    db, err := config.InitDatabase(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+    store, err := configureMedia(cfg.Media)
+    if err != nil {
+        return err
+    }
+    result, err := controllers.PurgeTrash(db, store, time.Now())
+    if err != nil {
+        return fmt.Errorf("failed to purge trash: %w", err)
+    }
+    log.Printf("Purged trash: %d recipes, %d reviews", result.Recipes, result.Feedbacks)
+    return nil
+}
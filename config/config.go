@@ -0,0 +1,294 @@
+package config
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "shei-deli/fixtures"
+    "shei-deli/models"
+    "shei-deli/moderation"
+    "shei-deli/storage"
+)
+
+// DefaultConfigFile is read at startup when CONFIG_FILE is unset; it is optional
+const DefaultConfigFile = "config.json"
+
+// Config holds the settings the server reads at startup
+type Config struct {
+    Server     ServerConfig     `json:"server"`
+    Database   DatabaseConfig   `json:"database"`
+    Media      MediaConfig      `json:"media"`
+    Moderation ModerationConfig `json:"moderation"`
+    Seed       SeedConfig       `json:"seed"`
+    Admin      AdminConfig      `json:"admin"`
+    LogLevel   string           `json:"log_level"` // debug, info, warn or error
+}
+
+// ServerConfig controls the HTTP listener and where pages and assets are read from
+type ServerConfig struct {
+    Addr        string `json:"addr"`
+    Mode        string `json:"mode"` // gin mode: debug, release or test
+    TemplateDir string `json:"template_dir"`
+    StaticDir   string `json:"static_dir"`
+    ImagesDir   string `json:"images_dir"`
+}
+
+// DatabaseConfig selects the database the server connects to
+type DatabaseConfig struct {
+    Driver      string `json:"driver"`       // sqlite, postgres or mysql
+    DSN         string `json:"dsn"`          // File name for sqlite, connection string otherwise
+    AutoMigrate bool   `json:"auto_migrate"` // Apply pending migrations at startup
+}
+
+// MediaConfig selects the store uploaded media is kept in
+type MediaConfig struct {
+    Backend string           `json:"backend"` // local or s3
+    Dir     string           `json:"dir"`     // Directory of the local store
+    S3      storage.S3Config `json:"s3"`      // Bucket of the s3 store
+}
+
+// ModerationConfig controls how submissions are screened
+type ModerationConfig struct {
+    FilterFile      string `json:"filter_file"`      // JSON filter definition; the built-in one when empty
+    RequireApproval bool   `json:"require_approval"` // Hold every new submission for review
+}
+
+// SeedConfig toggles the data created at startup
+type SeedConfig struct {
+    Admin         bool   `json:"admin"`          // Create the bootstrap admin account if it is missing
+    SampleRecipes bool   `json:"sample_recipes"` // Create the rows of Fixtures that are missing
+    Fixtures      string `json:"fixtures"`       // Built-in fixture set (demo, test or empty) or a fixture file
+}
+
+// AdminConfig holds the credentials of the bootstrap admin account. They are
+// only used when the account is created; an empty password is replaced by a
+// random one that is logged once.
+type AdminConfig struct {
+    Username string `json:"username"`
+    Email    string `json:"email"`
+    Password string `json:"password"`
+}
+
+var (
+    serverModes   = []string{"debug", "release", "test"}
+    drivers       = []string{DriverSQLite, DriverPostgres, DriverMySQL}
+    mediaBackends = []string{MediaLocal, MediaS3}
+    logLevels     = []string{"debug", "info", "warn", "error"}
+)
+
+// Media backends
+const (
+    MediaLocal = "local"
+    MediaS3    = "s3"
+)
+
+// Default returns the settings used when neither the config file nor the
+// environment override them
+func Default() Config {
+    return Config{
+        Server: ServerConfig{
+            Addr:        ":8080",
+            Mode:        "release",
+            TemplateDir: "templates",
+            StaticDir:   "static",
+            ImagesDir:   "images",
+        },
+        Database: DatabaseConfig{
+            Driver:      DriverSQLite,
+            DSN:         "shei_deli.db",
+            AutoMigrate: true,
+        },
+        Media: MediaConfig{
+            Backend: MediaLocal,
+            Dir:     "data/media",
+            S3:      storage.S3Config{Region: "us-east-1"},
+        },
+        Seed: SeedConfig{
+            Admin:         true,
+            SampleRecipes: true,
+            Fixtures:      "demo",
+        },
+        Admin: AdminConfig{
+            Username: "admin",
+            Email:    "admin@shei-deli.com",
+        },
+        LogLevel: "info",
+    }
+}
+
+// Load reads the config file named by CONFIG_FILE (or config.json if it
+// exists), applies environment overrides and validates the result
+func Load() (Config, error) {
+    path, required := os.Getenv("CONFIG_FILE"), true
+    if path == "" {
+        path, required = DefaultConfigFile, false
+    }
+    return LoadFile(path, required)
+}
+
+// LoadFile reads the config file at path over the defaults, applies
+// environment overrides and validates the result. A missing file is only an
+// error when required is set.
+func LoadFile(path string, required bool) (Config, error) {
+    cfg := Default()
+
+    data, err := os.ReadFile(path)
+    if err == nil {
+        decoder := json.NewDecoder(bytes.NewReader(data))
+        decoder.DisallowUnknownFields() // Catch misspelt settings instead of silently ignoring them
+        if err := decoder.Decode(&cfg); err != nil {
+            return cfg, fmt.Errorf("parse config %s: %w", path, err)
+        }
+    } else if required || !errors.Is(err, os.ErrNotExist) {
+        return cfg, fmt.Errorf("read config %s: %w", path, err)
+    }
+
+    if err := cfg.applyEnv(); err != nil {
+        return cfg, err
+    }
+    if err := cfg.Validate(); err != nil {
+        return cfg, err
+    }
+    return cfg, nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (cfg *Config) applyEnv() error {
+    texts := map[string]*string{
+        "LISTEN_ADDR":            &cfg.Server.Addr,
+        "GIN_MODE":               &cfg.Server.Mode,
+        "TEMPLATE_DIR":           &cfg.Server.TemplateDir,
+        "STATIC_DIR":             &cfg.Server.StaticDir,
+        "IMAGES_DIR":             &cfg.Server.ImagesDir,
+        "DATABASE_DRIVER":        &cfg.Database.Driver,
+        "DATABASE_DSN":           &cfg.Database.DSN,
+        "STORAGE_BACKEND":        &cfg.Media.Backend,
+        "MEDIA_DIR":              &cfg.Media.Dir,
+        "S3_ENDPOINT":            &cfg.Media.S3.Endpoint,
+        "S3_REGION":              &cfg.Media.S3.Region,
+        "S3_BUCKET":              &cfg.Media.S3.Bucket,
+        "S3_ACCESS_KEY":          &cfg.Media.S3.AccessKey,
+        "S3_SECRET_KEY":          &cfg.Media.S3.SecretKey,
+        "MODERATION_FILTER_FILE": &cfg.Moderation.FilterFile,
+        "SEED_FIXTURES":          &cfg.Seed.Fixtures,
+        "ADMIN_USERNAME":         &cfg.Admin.Username,
+        "ADMIN_EMAIL":            &cfg.Admin.Email,
+        "ADMIN_PASSWORD":         &cfg.Admin.Password,
+        "LOG_LEVEL":              &cfg.LogLevel,
+    }
+    for key, setting := range texts {
+        if value, ok := os.LookupEnv(key); ok {
+            *setting = value
+        }
+    }
+
+    bools := map[string]*bool{
+        "DATABASE_AUTO_MIGRATE":       &cfg.Database.AutoMigrate,
+        "S3_PATH_STYLE":               &cfg.Media.S3.PathStyle,
+        "MODERATION_REQUIRE_APPROVAL": &cfg.Moderation.RequireApproval,
+        "SEED_ADMIN":                  &cfg.Seed.Admin,
+        "SEED_SAMPLE_RECIPES":         &cfg.Seed.SampleRecipes,
+    }
+    for key, setting := range bools {
+        value, ok := os.LookupEnv(key)
+        if !ok {
+            continue
+        }
+        parsed, err := strconv.ParseBool(value)
+        if err != nil {
+            return fmt.Errorf("%s must be true or false, got %q", key, value)
+        }
+        *setting = parsed
+    }
+    return nil
+}
+
+// Validate reports every invalid setting at once
+func (cfg Config) Validate() error {
+    var errs []error
+    invalid := func(format string, args ...interface{}) {
+        errs = append(errs, fmt.Errorf(format, args...))
+    }
+
+    if _, _, err := net.SplitHostPort(cfg.Server.Addr); err != nil {
+        invalid("server.addr %q must be host:port or :port", cfg.Server.Addr)
+    }
+    if !contains(serverModes, cfg.Server.Mode) {
+        invalid("server.mode %q must be one of %s", cfg.Server.Mode, strings.Join(serverModes, ", "))
+    }
+    dirs := map[string]string{
+        "server.template_dir": cfg.Server.TemplateDir,
+        "server.static_dir":   cfg.Server.StaticDir,
+        "server.images_dir":   cfg.Server.ImagesDir,
+    }
+    for name, dir := range dirs {
+        if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+            invalid("%s %q must be an existing directory", name, dir)
+        }
+    }
+
+    if !contains(drivers, cfg.Database.Driver) {
+        invalid("database.driver %q must be one of %s", cfg.Database.Driver, strings.Join(drivers, ", "))
+    }
+    if cfg.Database.DSN == "" {
+        invalid("database.dsn is required")
+    }
+
+    switch cfg.Media.Backend {
+    case MediaLocal:
+        if cfg.Media.Dir == "" {
+            invalid("media.dir is required for the local backend")
+        }
+    case MediaS3:
+        if _, err := storage.NewS3Store(cfg.Media.S3); err != nil {
+            invalid("media.s3: %v", err)
+        }
+    default:
+        invalid("media.backend %q must be one of %s", cfg.Media.Backend, strings.Join(mediaBackends, ", "))
+    }
+    if cfg.Moderation.FilterFile != "" {
+        if _, err := moderation.LoadFilter(cfg.Moderation.FilterFile); err != nil {
+            invalid("moderation.filter_file: %v", err)
+        }
+    }
+
+    if cfg.Seed.Admin || cfg.Seed.SampleRecipes {
+        if cfg.Admin.Username == "" {
+            invalid("admin.username is required when seeding")
+        }
+        if !strings.Contains(cfg.Admin.Email, "@") {
+            invalid("admin.email %q must be an email address", cfg.Admin.Email)
+        }
+    }
+    if cfg.Seed.SampleRecipes && !contains(fixtures.Names(), cfg.Seed.Fixtures) {
+        if info, err := os.Stat(cfg.Seed.Fixtures); err != nil || info.IsDir() {
+            invalid("seed.fixtures %q must be one of %s or a fixture file", cfg.Seed.Fixtures, strings.Join(fixtures.Names(), ", "))
+        }
+    }
+    if cfg.Admin.Password != "" && !models.StrongPassword(cfg.Admin.Password) {
+        invalid("admin.password must be 8 to 72 characters with a letter and a number")
+    }
+
+    if !contains(logLevels, cfg.LogLevel) {
+        invalid("log_level %q must be one of %s", cfg.LogLevel, strings.Join(logLevels, ", "))
+    }
+
+    if len(errs) > 0 {
+        return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+    }
+    return nil
+}
+
+func contains(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
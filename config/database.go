@@ -2,26 +2,124 @@
 package config
 
 import (
+    "fmt"
     "log"
+    mysqldriver "github.com/go-sql-driver/mysql"
+    "gorm.io/driver/mysql"
+    "gorm.io/driver/postgres"
     "gorm.io/driver/sqlite"
     "gorm.io/gorm"
+    "shei-deli/migrations"
     "shei-deli/models"
 )
 
-var DB *gorm.DB
+// Database drivers accepted in DatabaseConfig.Driver
+const (
+    DriverSQLite   = "sqlite"
+    DriverPostgres = "postgres"
+    DriverMySQL    = "mysql"
+)
+
+// OpenDatabase connects to the database selected by cfg.Driver
+func OpenDatabase(cfg DatabaseConfig) (*gorm.DB, error) {
+    var dialector gorm.Dialector
+    switch cfg.Driver {
+    case DriverSQLite, "":
+        dialector = sqlite.Open(cfg.DSN)
+    case DriverPostgres:
+        dialector = postgres.Open(cfg.DSN)
+    case DriverMySQL:
+        dsn, err := mysqldriver.ParseDSN(cfg.DSN)
+        if err != nil {
+            return nil, fmt.Errorf("parse mysql dsn: %w", err)
+        }
+        dsn.ParseTime = true // Scan DATETIME columns into time.Time
+        dialector = mysql.New(mysql.Config{DSNConfig: dsn})
+    default:
+        return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+    }
+
+    return gorm.Open(dialector, &gorm.Config{
+        // SQLite doesn't enforce foreign keys by default and the models rely
+        // on that (e.g. reviews of trashed recipes), so keep every dialect alike
+        DisableForeignKeyConstraintWhenMigrating: true,
+    })
+}
 
-func InitDatabase() {
-    var err error
-    DB, err = gorm.Open(sqlite.Open("shei_deli.db"), &gorm.Config{})
+// CloseDatabase closes the connection opened by OpenDatabase, InitDatabase or ResetDatabase
+func CloseDatabase(db *gorm.DB) {
+    if sqlDB, err := db.DB(); err == nil {
+        sqlDB.Close()
+    }
+}
+
+// InitDatabase connects to the database in cfg and brings the schema up to
+// date, or refuses to start with pending migrations when cfg.AutoMigrate is off
+func InitDatabase(cfg DatabaseConfig) (*gorm.DB, error) {
+    db, err := OpenDatabase(cfg)
     if err != nil {
-        log.Fatalf("Failed to connect to database: %v", err)
+        return nil, fmt.Errorf("failed to connect to database: %w", err)
     }
+    if err := prepareDatabase(db, cfg.AutoMigrate); err != nil {
+        CloseDatabase(db)
+        return nil, err
+    }
+    return db, nil
+}
 
-    // Auto-migrate the schema
-    err = DB.AutoMigrate(&models.Recipe{}, &models.Feedback{}, &models.User{})
+// ResetDatabase connects to the database in cfg, drops every table by
+// reverting all migrations and builds the schema again
+func ResetDatabase(cfg DatabaseConfig) (*gorm.DB, error) {
+    db, err := OpenDatabase(cfg)
     if err != nil {
-        log.Fatalf("Failed to migrate database: %v", err)
+        return nil, fmt.Errorf("failed to connect to database: %w", err)
+    }
+    reverted, err := migrations.Down(db, len(migrations.All()))
+    if err != nil {
+        CloseDatabase(db)
+        return nil, fmt.Errorf("failed to reset database: %w", err)
+    }
+    log.Printf("Reset database: reverted %d migrations", len(reverted))
+    if err := prepareDatabase(db, true); err != nil {
+        CloseDatabase(db)
+        return nil, err
+    }
+    return db, nil
+}
+
+// prepareDatabase migrates the schema (or checks nothing is pending) and
+// creates the default categories and cuisines
+func prepareDatabase(db *gorm.DB, autoMigrate bool) error {
+    if autoMigrate {
+        applied, err := migrations.Up(db)
+        for _, m := range applied {
+            log.Printf("Applied migration %d_%s", m.Version, m.Name)
+        }
+        if err != nil {
+            return fmt.Errorf("failed to migrate database: %w", err)
+        }
+    } else {
+        pending, err := migrations.Pending(db)
+        if err != nil {
+            return fmt.Errorf("failed to check migrations: %w", err)
+        }
+        if len(pending) > 0 {
+            return fmt.Errorf("database has %d pending migrations; run `shei-deli migrate up` first", len(pending))
+        }
+    }
+
+    // Recipes reference categories and cuisines, so the defaults must exist before anything else runs
+    if created, err := models.SeedCategories(db); err != nil {
+        return fmt.Errorf("failed to seed categories: %w", err)
+    } else if created > 0 {
+        log.Printf("Created %d default categories", created)
+    }
+    if created, err := models.SeedCuisines(db); err != nil {
+        return fmt.Errorf("failed to seed cuisines: %w", err)
+    } else if created > 0 {
+        log.Printf("Created %d default cuisines", created)
     }
 
     log.Println("Database connected and migrated!")
+    return nil
 }
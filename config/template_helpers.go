@@ -3,10 +3,14 @@ package config
 import (
     "html/template"
     "strings"
+    "shei-deli/i18n"
+    "shei-deli/models"
+    "shei-deli/repository"
 )
 
-// GetTemplateFunctions returns template helper functions
-func GetTemplateFunctions() template.FuncMap {
+// GetTemplateFunctions returns template helper functions; category and
+// cuisine names are looked up in taxonomy
+func GetTemplateFunctions(taxonomy repository.TaxonomyRepository) template.FuncMap {
     return template.FuncMap{
         "add": func(a, b int) int {
             return a + b
@@ -35,5 +39,21 @@ func GetTemplateFunctions() template.FuncMap {
             emptyStars := strings.Repeat("☆", 5-fullStars)
             return template.HTML(stars + emptyStars)
         },
+        // t translates a message key into the page's locale, e.g. {{t $.Locale "nav.home"}}
+        "t": func(locale, key string, args ...interface{}) string {
+            return i18n.T(locale, key, args...)
+        },
+        // categoryName returns the display name of a recipe's category in locale
+        "categoryName": func(locale string, key models.RecipeCategory) string {
+            return taxonomy.CategoryName(key, locale)
+        },
+        // cuisineName returns the display name of a recipe's cuisine
+        "cuisineName": func(key string) string {
+            return taxonomy.CuisineName(key)
+        },
+        // canReply reports whether a reply at this depth may itself be answered
+        "canReply": func(depth int) bool {
+            return depth < models.MaxReplyDepth
+        },
     }
 }
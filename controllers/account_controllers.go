@@ -0,0 +1,212 @@
+package controllers
+
+import (
+    "archive/zip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "time"
+    "shei-deli/apperrors"
+    "shei-deli/models"
+    "shei-deli/repository"
+    "shei-deli/storage"
+    "github.com/gin-gonic/gin"
+)
+
+// AccountController serves account deactivation, deletion and data export
+type AccountController struct {
+    users   repository.UserRepository
+    uploads uploads
+}
+
+// NewAccountController builds an AccountController from s
+func NewAccountController(s Services) *AccountController {
+    return &AccountController{users: s.Users, uploads: newUploads(s.Media)}
+}
+
+// AccountDeletion represents the account deletion request; content chooses
+// whether the user's recipes and reviews are anonymized or removed
+type AccountDeletion struct {
+    Content string `form:"content" binding:"required,oneof=anonymize remove"`
+}
+
+// DeactivateAccount closes an account without deleting anything; the user can
+// no longer sign in until the account is reactivated
+func (ac *AccountController) DeactivateAccount(c *gin.Context) {
+    ac.setAccountActive(c, false, "Account deactivated")
+}
+
+// ReactivateAccount reopens a deactivated account
+func (ac *AccountController) ReactivateAccount(c *gin.Context) {
+    ac.setAccountActive(c, true, "Account reactivated")
+}
+
+func (ac *AccountController) setAccountActive(c *gin.Context, active bool, message string) {
+    user, err := ac.findAccount(c.Param("id"))
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    if err := ac.users.SetActive(&user, active); err != nil {
+        c.Error(apperrors.Internal("Error updating account", err))
+        return
+    }
+
+    user.Password = ""
+    c.JSON(http.StatusOK, gin.H{
+        "message": message,
+        "user":    user,
+    })
+}
+
+// DeleteAccount permanently deletes an account. Personal data is scrubbed in
+// both modes; content=anonymize keeps the user's recipes and reviews under
+// "Former member", content=remove deletes them along with uploaded images.
+func (ac *AccountController) DeleteAccount(c *gin.Context) {
+    var req AccountDeletion
+    if err := c.ShouldBindQuery(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    user, apiErr := ac.findAccount(c.Param("id"))
+    if apiErr != nil {
+        c.Error(apiErr)
+        return
+    }
+    if user.Role == models.RoleAdmin {
+        c.Error(apperrors.Forbidden("Admin accounts cannot be deleted"))
+        return
+    }
+
+    removed, err := ac.users.Delete(&user, req.Content == models.DeleteRemove, time.Now().UTC())
+    if err != nil {
+        c.Error(apperrors.Internal("Error deleting account", err))
+        return
+    }
+    ac.uploads.remove(removed.MediaURLs)
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":           "Account deleted successfully",
+        "content":           req.Content,
+        "recipes_removed":   removed.Recipes,
+        "feedbacks_removed": removed.Feedbacks,
+    })
+}
+
+// ExportAccount sends a ZIP archive with everything stored about a user,
+// including deleted items still in the trash, and the images they uploaded
+func (ac *AccountController) ExportAccount(c *gin.Context) {
+    user, apiErr := ac.findAccount(c.Param("id"))
+    if apiErr != nil {
+        c.Error(apiErr)
+        return
+    }
+
+    export, err := ac.users.Export(user)
+    if err != nil {
+        c.Error(apperrors.Internal("Error collecting account data", err))
+        return
+    }
+
+    // Everything below streams straight into the response, so from here on
+    // errors can only be logged
+    c.Header("Content-Type", "application/zip")
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="shei-deli-%s-export.zip"`, user.Username))
+    c.Status(http.StatusOK)
+
+    archive := zip.NewWriter(c.Writer)
+    files := []struct {
+        name string
+        data interface{}
+    }{
+        {"profile.json", export.Profile},
+        {"recipes.json", export.Recipes},
+        {"feedback.json", export.Feedbacks},
+        {"replies.json", export.Replies},
+        {"votes.json", export.Votes},
+        {"reports.json", export.Reports},
+    }
+    for _, file := range files {
+        if err := writeJSONEntry(archive, file.name, file.data); err != nil {
+            log.Printf("Failed to export %s for user %d: %v", file.name, user.ID, err)
+        }
+    }
+    for _, key := range exportImageKeys(export) {
+        if err := writeMediaEntry(c, ac.uploads.store, archive, key); err != nil {
+            log.Printf("Failed to export image %s for user %d: %v", key, user.ID, err)
+        }
+    }
+    if err := archive.Close(); err != nil {
+        log.Printf("Failed to finish export for user %d: %v", user.ID, err)
+    }
+}
+
+// findAccount loads a user that has not been deleted yet
+func (ac *AccountController) findAccount(param string) (models.User, *apperrors.Error) {
+    id, apiErr := parseID(param, "User")
+    if apiErr != nil {
+        return models.User{}, apiErr
+    }
+    user, err := ac.users.Find(id)
+    if err != nil {
+        return user, apperrors.FromDB(err, "User")
+    }
+    if user.IsAnonymized() {
+        return user, apperrors.Gone("This account has been deleted")
+    }
+    return user, nil
+}
+
+// exportImageKeys lists the media keys of the images a user uploaded
+func exportImageKeys(export repository.UserExport) []string {
+    var urls []string
+    for _, r := range export.Recipes {
+        urls = append(urls, r.ImageURL)
+    }
+    for _, f := range export.Feedbacks {
+        for _, p := range f.Photos {
+            urls = append(urls, p.URL)
+        }
+    }
+
+    seen := make(map[string]bool)
+    var keys []string
+    for _, url := range urls {
+        key, ok := storage.KeyFromURL(url)
+        if !ok || seen[key] {
+            continue
+        }
+        seen[key] = true
+        keys = append(keys, key)
+    }
+    return keys
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, data interface{}) error {
+    w, err := archive.Create(name)
+    if err != nil {
+        return err
+    }
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(data)
+}
+
+func writeMediaEntry(c *gin.Context, store storage.BlobStore, archive *zip.Writer, key string) error {
+    blob, err := store.Get(c.Request.Context(), key)
+    if err != nil {
+        return err
+    }
+    defer blob.Close()
+
+    w, err := archive.Create("images/" + key)
+    if err != nil {
+        return err
+    }
+    _, err = io.Copy(w, blob)
+    return err
+}
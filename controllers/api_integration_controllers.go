@@ -2,16 +2,30 @@ package controllers
 
 import (
     "encoding/json"
+    "errors"
     "fmt"
     "net/http"
     "net/url"
     "strconv"
     "strings"
     "time"
+    "shei-deli/apperrors"
+    "shei-deli/middleware"
     "shei-deli/models"
+    "shei-deli/repository"
     "github.com/gin-gonic/gin"
 )
 
+// ExternalController serves recipe searches against the external recipe APIs
+type ExternalController struct {
+    taxonomy repository.TaxonomyRepository
+}
+
+// NewExternalController builds an ExternalController from s
+func NewExternalController(s Services) *ExternalController {
+    return &ExternalController{taxonomy: s.Taxonomy}
+}
+
 // API Configuration
 const (
     SpoonacularAPIKey = "095a1d67a1ac14b20872de17b6b95749" // Spoonacular API key
@@ -149,6 +163,19 @@ func GetCategoryAPIMapping() map[models.RecipeCategory]CategoryAPIMapping {
                 Health:      "high-protein",
             },
         },
+        models.Stews: {
+            Category: models.Stews,
+            Spoonacular: SpoonacularParams{
+                Query: "stew",
+                Type:  "main course",
+            },
+            Edamam: EdamamParams{
+                Query: "stew",
+            },
+            TheMealDB: TheMealDBParams{
+                Query: "stew",
+            },
+        },
         models.MeatStews: {
             Category: models.MeatStews,
             Spoonacular: SpoonacularParams{
@@ -342,8 +369,9 @@ func cleanHTMLTags(text string) string {
     return strings.TrimSpace(result)
 }
 
-// getEnhancedMockRecipes returns realistic mock data for each category
-func getEnhancedMockRecipes(category models.RecipeCategory, limit int) []ExternalRecipe {
+// getEnhancedMockRecipes returns realistic mock data for each category;
+// categoryName is the category's display name
+func getEnhancedMockRecipes(category models.RecipeCategory, categoryName string, limit int) []ExternalRecipe {
     mockData := map[models.RecipeCategory][]ExternalRecipe{
         models.PlantBasedMeals: {
             {ID: "mock_pb_1", Title: "Quinoa Buddha Bowl", Description: "Nutritious bowl with quinoa, roasted vegetables, and tahini dressing", Image: "/images/plant-based.jpg", ReadyTime: 25, Servings: 2, Source: "Plant-Based Kitchen", SourceURL: "https://example.com/quinoa-bowl", Ingredients: []string{"Quinoa", "Sweet potato", "Chickpeas", "Tahini"}, Instructions: "Cook quinoa, roast vegetables, assemble bowl with tahini dressing"},
@@ -374,8 +402,8 @@ func getEnhancedMockRecipes(category models.RecipeCategory, limit int) []Externa
 
     // Add default recipes for categories not explicitly defined
     defaultRecipes := []ExternalRecipe{
-        {ID: "mock_def_1", Title: fmt.Sprintf("Delicious %s Recipe", category.GetDisplayName()), Description: fmt.Sprintf("A wonderful %s recipe from our curated collection", strings.ToLower(category.GetDisplayName())), Image: fmt.Sprintf("/images/%s.jpg", strings.ReplaceAll(strings.ToLower(category.GetDisplayName()), " ", "-")), ReadyTime: 30, Servings: 4, Source: "Recipe Collection", SourceURL: "https://example.com/recipe", Ingredients: []string{"Fresh ingredients", "Quality seasonings", "Love and care"}, Instructions: "Follow traditional cooking methods for best results"},
-        {ID: "mock_def_2", Title: fmt.Sprintf("Classic %s Dish", category.GetDisplayName()), Description: fmt.Sprintf("Traditional %s preparation with modern touches", strings.ToLower(category.GetDisplayName())), Image: fmt.Sprintf("/images/%s.jpg", strings.ReplaceAll(strings.ToLower(category.GetDisplayName()), " ", "-")), ReadyTime: 45, Servings: 6, Source: "Traditional Kitchen", SourceURL: "https://example.com/classic", Ingredients: []string{"Traditional ingredients", "Authentic spices", "Time-tested methods"}, Instructions: "Prepare using time-honored techniques"},
+        {ID: "mock_def_1", Title: fmt.Sprintf("Delicious %s Recipe", categoryName), Description: fmt.Sprintf("A wonderful %s recipe from our curated collection", strings.ToLower(categoryName)), Image: fmt.Sprintf("/images/%s.jpg", strings.ReplaceAll(strings.ToLower(categoryName), " ", "-")), ReadyTime: 30, Servings: 4, Source: "Recipe Collection", SourceURL: "https://example.com/recipe", Ingredients: []string{"Fresh ingredients", "Quality seasonings", "Love and care"}, Instructions: "Follow traditional cooking methods for best results"},
+        {ID: "mock_def_2", Title: fmt.Sprintf("Classic %s Dish", categoryName), Description: fmt.Sprintf("Traditional %s preparation with modern touches", strings.ToLower(categoryName)), Image: fmt.Sprintf("/images/%s.jpg", strings.ReplaceAll(strings.ToLower(categoryName), " ", "-")), ReadyTime: 45, Servings: 6, Source: "Traditional Kitchen", SourceURL: "https://example.com/classic", Ingredients: []string{"Traditional ingredients", "Authentic spices", "Time-tested methods"}, Instructions: "Prepare using time-honored techniques"},
     }
 
     recipes, exists := mockData[category]
@@ -392,17 +420,35 @@ func getEnhancedMockRecipes(category models.RecipeCategory, limit int) []Externa
 }
 
 // SearchExternalRecipes searches external APIs for recipes based on category
-func SearchExternalRecipes(c *gin.Context) {
+func (ec *ExternalController) SearchExternalRecipes(c *gin.Context) {
     categoryStr := c.Param("category")
 
-    if !models.IsValidCategory(categoryStr) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+    categoryInfo, err := ec.taxonomy.FindCategory(categoryStr)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            c.Error(apperrors.BadRequest("Invalid category"))
+            return
+        }
+        c.Error(apperrors.Internal("Error retrieving category", err))
         return
     }
 
     category := models.RecipeCategory(categoryStr)
     mapping := GetCategoryAPIMapping()[category]
 
+    // ?cuisine= narrows the search to one of our cuisines, whose names match
+    // the external APIs' cuisines and areas
+    if key := c.Query("cuisine"); key != "" {
+        cuisine, err := ec.taxonomy.FindCuisine(key)
+        if err != nil {
+            c.Error(apperrors.BadRequest("Invalid cuisine"))
+            return
+        }
+        mapping.Spoonacular.Cuisine = cuisine.Name
+        mapping.Edamam.CuisineType = cuisine.Name
+        mapping.TheMealDB.Area = cuisine.Name
+    }
+
     // Get limit from query parameter (default: 12, max: 50)
     limit := 12
     if l := c.Query("limit"); l != "" {
@@ -415,10 +461,10 @@ func SearchExternalRecipes(c *gin.Context) {
     externalRecipes, err := fetchSpoonacularRecipes(mapping.Spoonacular, limit)
     if err != nil {
         // If API call fails, return enhanced mock data as fallback
-        mockRecipes := getEnhancedMockRecipes(category, limit)
+        mockRecipes := getEnhancedMockRecipes(category, categoryInfo.Name, limit)
 
         c.JSON(http.StatusOK, gin.H{
-            "category":         category.GetDisplayName(),
+            "category":         categoryInfo.Localized(middleware.GetLocale(c)).Name,
             "api_mapping":      mapping,
             "external_recipes": mockRecipes,
             "note":            "Using enhanced mock data. Real API integration ready - API key may need activation.",
@@ -428,7 +474,7 @@ func SearchExternalRecipes(c *gin.Context) {
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "category":         category.GetDisplayName(),
+        "category":         categoryInfo.Localized(middleware.GetLocale(c)).Name,
         "api_mapping":      mapping,
         "external_recipes": externalRecipes,
         "count":           len(externalRecipes),
@@ -437,13 +483,13 @@ func SearchExternalRecipes(c *gin.Context) {
 }
 
 // GetAPIMappingInfo returns the API mapping configuration for all categories
-func GetAPIMappingInfo(c *gin.Context) {
+func (ec *ExternalController) GetAPIMappingInfo(c *gin.Context) {
     mappings := GetCategoryAPIMapping()
     
     response := make(map[string]interface{})
     for category, mapping := range mappings {
         response[string(category)] = gin.H{
-            "category_name": category.GetDisplayName(),
+            "category_name": ec.taxonomy.CategoryName(category, middleware.GetLocale(c)),
             "spoonacular":   mapping.Spoonacular,
             "edamam":        mapping.Edamam,
             "themealdb":     mapping.TheMealDB,
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+    "net/http"
+    "shei-deli/apperrors"
+    "shei-deli/middleware"
+    "shei-deli/models"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+)
+
+// CategoryController serves the category and cuisine taxonomies
+type CategoryController struct {
+    taxonomy repository.TaxonomyRepository
+}
+
+// NewCategoryController builds a CategoryController from s
+func NewCategoryController(s Services) *CategoryController {
+    return &CategoryController{taxonomy: s.Taxonomy}
+}
+
+// CategoryRequest represents the create category request
+type CategoryRequest struct {
+    Key         string `json:"key" binding:"required,max=50,slug"`
+    ParentKey   string `json:"parent_key" binding:"omitempty,max=50"`
+    Name        string `json:"name" binding:"required,max=100"`
+    Description string `json:"description" binding:"max=500"`
+    Image       string `json:"image" binding:"max=500"`
+    SortOrder   int    `json:"sort_order"`
+}
+
+// CategoryUpdate represents the update category request; empty fields are
+// left unchanged. The key cannot change because recipes reference it.
+// parent_key moves the category; an empty string makes it top-level.
+type CategoryUpdate struct {
+    ParentKey   *string `json:"parent_key" binding:"omitempty,max=50"`
+    Name        string `json:"name" binding:"max=100"`
+    Description string `json:"description" binding:"max=500"`
+    Image       string `json:"image" binding:"max=500"`
+    SortOrder   *int   `json:"sort_order"`
+}
+
+// GetCategories lists all categories in display order, named in the request's
+// language; with ?tree=true only top-level categories are listed, with their
+// subcategories nested inside
+func (cc *CategoryController) GetCategories(c *gin.Context) {
+    categories, err := cc.taxonomy.Categories()
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving categories", err))
+        return
+    }
+    if c.Query("tree") == "true" {
+        categories = models.CategoryTree(categories)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "categories": models.LocalizeCategories(categories, middleware.GetLocale(c)),
+    })
+}
+
+// GetCategory fetches a single category by key with its subcategories nested inside
+func (cc *CategoryController) GetCategory(c *gin.Context) {
+    category, err := cc.taxonomy.FindCategory(c.Param("key"))
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Category"))
+        return
+    }
+
+    family, err := cc.taxonomy.Family(category.Key)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving subcategories", err))
+        return
+    }
+    for _, root := range models.CategoryTree(family) {
+        if root.Key == category.Key {
+            category = root
+        }
+    }
+
+    c.JSON(http.StatusOK, category.Localized(middleware.GetLocale(c)))
+}
+
+// CreateCategory adds a new category (admin)
+func (cc *CategoryController) CreateCategory(c *gin.Context) {
+    var req CategoryRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    if cc.taxonomy.IsCategory(req.Key) {
+        c.Error(apperrors.Conflict("A category with this key already exists"))
+        return
+    }
+    if req.ParentKey != "" && !cc.taxonomy.IsCategory(req.ParentKey) {
+        respondValidationError(c, []FieldError{{Field: "parent_key", Message: "must be a valid category"}})
+        return
+    }
+
+    category := models.Category{
+        Key:         req.Key,
+        ParentKey:   req.ParentKey,
+        Name:        req.Name,
+        Description: req.Description,
+        Image:       req.Image,
+        SortOrder:   req.SortOrder,
+    }
+    if err := cc.taxonomy.CreateCategory(&category); err != nil {
+        c.Error(apperrors.Internal("Error creating category", err))
+        return
+    }
+
+    c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory changes a category's name, description, image or sort order (admin)
+func (cc *CategoryController) UpdateCategory(c *gin.Context) {
+    category, err := cc.taxonomy.FindCategory(c.Param("key"))
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Category"))
+        return
+    }
+
+    var req CategoryUpdate
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    updates := map[string]interface{}{}
+    if req.ParentKey != nil {
+        if err := cc.checkCategoryParent(category.Key, *req.ParentKey); err != nil {
+            c.Error(err)
+            return
+        }
+        updates["parent_key"] = *req.ParentKey
+    }
+    if req.Name != "" {
+        updates["name"] = req.Name
+    }
+    if req.Description != "" {
+        updates["description"] = req.Description
+    }
+    if req.Image != "" {
+        updates["image"] = req.Image
+    }
+    if req.SortOrder != nil {
+        updates["sort_order"] = *req.SortOrder
+    }
+    if len(updates) > 0 {
+        if err := cc.taxonomy.UpdateCategory(&category, updates); err != nil {
+            c.Error(apperrors.Internal("Error updating category", err))
+            return
+        }
+    }
+
+    c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory removes a category that no recipe uses, including recipes
+// in the trash (admin)
+func (cc *CategoryController) DeleteCategory(c *gin.Context) {
+    category, err := cc.taxonomy.FindCategory(c.Param("key"))
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Category"))
+        return
+    }
+
+    inUse, err := cc.taxonomy.CategoryInUse(category.Key)
+    if err != nil {
+        c.Error(apperrors.Internal("Error checking category recipes", err))
+        return
+    }
+    if inUse {
+        c.Error(apperrors.Conflict("Category still has recipes; move them to another category first"))
+        return
+    }
+    keys, err := cc.taxonomy.CategoryKeys(category.Key)
+    if err != nil {
+        c.Error(apperrors.Internal("Error checking subcategories", err))
+        return
+    }
+    if len(keys) > 1 {
+        c.Error(apperrors.Conflict("Category still has subcategories; move or delete them first"))
+        return
+    }
+
+    // Hard delete so the key can be reused
+    if err := cc.taxonomy.DeleteCategory(&category); err != nil {
+        c.Error(apperrors.Internal("Error deleting category", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Category deleted successfully",
+    })
+}
+
+// checkCategoryParent rejects parents that do not exist or would put a
+// category inside itself
+func (cc *CategoryController) checkCategoryParent(key, parentKey string) error {
+    if parentKey == "" {
+        return nil
+    }
+    if !cc.taxonomy.IsCategory(parentKey) {
+        return apperrors.Validation([]FieldError{{Field: "parent_key", Message: "must be a valid category"}})
+    }
+
+    keys, err := cc.taxonomy.CategoryKeys(key)
+    if err != nil {
+        return apperrors.Internal("Error checking subcategories", err)
+    }
+    for _, k := range keys {
+        if k == parentKey {
+            return apperrors.Validation([]FieldError{{Field: "parent_key", Message: "cannot be the category itself or one of its subcategories"}})
+        }
+    }
+    return nil
+}
+
+// GetCuisines lists all cuisines in display order
+func (cc *CategoryController) GetCuisines(c *gin.Context) {
+    cuisines, err := cc.taxonomy.Cuisines()
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving cuisines", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "cuisines": cuisines,
+    })
+}
@@ -1,61 +1,130 @@
 package controllers
 
 import (
+    "errors"
+    "fmt"
+    "mime/multipart"
     "net/http"
     "strconv"
+    "strings"
+    "time"
+    "shei-deli/apperrors"
     "shei-deli/models"
-    "shei-deli/config"
+    "shei-deli/moderation"
+    "shei-deli/repository"
     "github.com/gin-gonic/gin"
 )
 
+// FeedbackController serves reviews and their photos, replies and votes
+type FeedbackController struct {
+    recipes  repository.RecipeRepository
+    feedback repository.FeedbackRepository
+    users    repository.UserRepository
+    uploads  uploads
+    filter   *moderation.Filter
+}
+
+// NewFeedbackController builds a FeedbackController from s
+func NewFeedbackController(s Services) *FeedbackController {
+    return &FeedbackController{recipes: s.Recipes, feedback: s.Feedback, users: s.Users, uploads: newUploads(s.Media), filter: s.Filter}
+}
+
+// FeedbackRequest represents the payload for adding feedback to a recipe
+type FeedbackRequest struct {
+    RecipeID uint   `json:"recipe_id" binding:"required"`
+    UserID   uint   `json:"user_id"`
+    Comment  string `json:"comment" binding:"max=2000"`
+    Rating   int    `json:"rating" binding:"required,min=1,max=5"`
+}
+
+// FeedbackUpdateRequest represents the payload for updating feedback; empty fields are left unchanged
+type FeedbackUpdateRequest struct {
+    Comment string `json:"comment" binding:"max=2000"`
+    Rating  int    `json:"rating" binding:"omitempty,min=1,max=5"`
+}
+
+// FeedbackReplyRequest represents a reply to a review or to another reply in its thread
+type FeedbackReplyRequest struct {
+    UserID   uint   `json:"user_id"`
+    ParentID *uint  `json:"parent_id"`
+    Comment  string `json:"comment" binding:"required,max=2000"`
+}
+
+// FeedbackVoteRequest represents a user marking someone else's review helpful or unhelpful
+type FeedbackVoteRequest struct {
+    UserID  uint  `json:"user_id"`
+    Helpful *bool `json:"helpful" binding:"required"`
+}
+
 // GetRecipeFeedback fetches all feedback for a specific recipe
-func GetRecipeFeedback(c *gin.Context) {
-    recipeID := c.Param("recipeId")
+func (fc *FeedbackController) GetRecipeFeedback(c *gin.Context) {
+    recipeID, err := paramID(c, "recipeId", "Recipe")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
     // Check if recipe exists
-    var recipe models.Recipe
-    if err := config.DB.First(&recipe, recipeID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+    recipe, err := fc.recipes.FindApproved(recipeID)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
         return
     }
     
-    var feedbacks []models.Feedback
-    if err := config.DB.Preload("User").Where("recipe_id = ?", recipeID).Order("created_at DESC").Find(&feedbacks).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving feedback"})
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    sortOpt, err := parseFeedbackSort(c)
+    if err != nil {
+        c.Error(err)
         return
     }
     
-    // Calculate average rating
-    var avgRating float64
-    var totalRatings int64
-    config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipeID).Select("AVG(rating)").Scan(&avgRating)
-    config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipeID).Count(&totalRatings)
+    feedbacks, pageInfo, err := fc.feedback.ForRecipe(recipe.ID, pageReq, sortOpt)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving feedback"))
+        return
+    }
+    linkPage(c, &pageInfo)
     
     c.JSON(http.StatusOK, gin.H{
         "feedbacks":      feedbacks,
-        "average_rating": avgRating,
-        "total_ratings":  totalRatings,
+        "average_rating": recipe.AverageRating,
+        "total_ratings":  recipe.RatingCount,
+        "sort":           sortOpt.Key,
+        "pagination":     pageInfo,
     })
 }
 
-// AddFeedback adds feedback to a recipe
-func AddFeedback(c *gin.Context) {
-    var feedback models.Feedback
-    if err := c.ShouldBindJSON(&feedback); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feedback format"})
+// AddFeedback adds a user's review of a recipe, or replaces their existing one.
+// Multipart requests may attach photos of the cooked dish in "photos".
+func (fc *FeedbackController) AddFeedback(c *gin.Context) {
+    var req FeedbackRequest
+    var photoHeaders []*multipart.FileHeader
+    if strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data") {
+        var fields []FieldError
+        req, photoHeaders, fields = bindFeedbackForm(c)
+        if len(fields) > 0 {
+            respondValidationError(c, fields)
+            return
+        }
+    } else if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
         return
     }
     
-    // Validate rating
-    if !feedback.IsValidRating() {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
-        return
+    feedback := models.Feedback{
+        RecipeID: req.RecipeID,
+        UserID:   req.UserID,
+        Comment:  req.Comment,
+        Rating:   req.Rating,
     }
     
     // Check if recipe exists
-    var recipe models.Recipe
-    if err := config.DB.First(&recipe, feedback.RecipeID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+    if _, err := fc.recipes.FindApproved(feedback.RecipeID); err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
         return
     }
     
@@ -65,102 +134,362 @@ func AddFeedback(c *gin.Context) {
     }
     
     // Check if user exists
-    var user models.User
-    if err := config.DB.First(&user, feedback.UserID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+    if _, err := activeUser(fc.users, feedback.UserID); err != nil {
+        c.Error(err)
         return
     }
     
-    if err := config.DB.Create(&feedback).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving feedback"})
+    // Each user has one review per recipe: reviewing again replaces the
+    // rating and comment and adds any new photos, and a deleted review is restored.
+    existing, err := fc.feedback.FindByAuthor(feedback.RecipeID, feedback.UserID)
+    found := err == nil
+    if err != nil && !errors.Is(err, repository.ErrNotFound) {
+        c.Error(apperrors.Internal("Error saving feedback", err))
         return
     }
     
-    // Load relationships for response
-    config.DB.Preload("User").Preload("Recipe").First(&feedback, feedback.ID)
+    // Store photos before touching the database so a bad upload rejects the
+    // whole review; they are removed again if saving the review fails
+    var photos []models.FeedbackPhoto
+    var photoURLs []string
+    for _, header := range photoHeaders {
+        photo, err := fc.uploads.save(header, "photos", "review")
+        if err != nil {
+            fc.uploads.remove(photoURLs)
+            c.Error(err)
+            return
+        }
+        photos = append(photos, models.FeedbackPhoto{URL: photo.URL, ThumbnailURL: photo.ThumbnailURL})
+        photoURLs = append(photoURLs, photo.URL)
+    }
     
-    c.JSON(http.StatusCreated, feedback)
+    // Only approved reviews count towards the recipe's rating
+    status := http.StatusCreated
+    if found {
+        if !existing.DeletedAt.Valid {
+            status = http.StatusOK
+        }
+        feedback.Status, feedback.ModerationNote = fc.filter.Status(existing.Status, existing.ModerationNote, feedback.Comment)
+        err = fc.feedback.Replace(existing, &feedback, photos)
+    } else {
+        feedback.Status, feedback.ModerationNote = fc.filter.Status("", "", feedback.Comment)
+        err = fc.feedback.Create(&feedback, photos)
+    }
+    if err != nil {
+        fc.uploads.remove(photoURLs)
+        c.Error(apperrors.Internal("Error saving feedback", err))
+        return
+    }
+    
+    c.JSON(status, feedback)
+}
+
+// bindFeedbackForm reads a multipart review form and its photos, reporting
+// unparseable numbers per field
+func bindFeedbackForm(c *gin.Context) (FeedbackRequest, []*multipart.FileHeader, []FieldError) {
+    req := FeedbackRequest{Comment: c.PostForm("comment")}
+    
+    var fields []FieldError
+    numericFields := []struct {
+        name string
+        dst  *uint
+    }{
+        {"recipe_id", &req.RecipeID},
+        {"user_id", &req.UserID},
+    }
+    for _, nf := range numericFields {
+        value := c.PostForm(nf.name)
+        if value == "" {
+            continue
+        }
+        n, err := strconv.ParseUint(value, 10, 32)
+        if err != nil {
+            fields = append(fields, FieldError{Field: nf.name, Message: "must be a positive whole number"})
+            continue
+        }
+        *nf.dst = uint(n)
+    }
+    if value := c.PostForm("rating"); value != "" {
+        rating, err := strconv.Atoi(value)
+        if err != nil {
+            fields = append(fields, FieldError{Field: "rating", Message: "must be a whole number"})
+        }
+        req.Rating = rating
+    }
+    
+    var photos []*multipart.FileHeader
+    if form, err := c.MultipartForm(); err == nil {
+        for _, header := range form.File["photos"] {
+            // Browsers send an empty part when no file was chosen
+            if header.Filename == "" && header.Size == 0 {
+                continue
+            }
+            photos = append(photos, header)
+        }
+    }
+    if len(photos) > maxReviewPhotos {
+        fields = append(fields, FieldError{Field: "photos", Message: fmt.Sprintf("must contain at most %d photos", maxReviewPhotos)})
+    }
+    
+    return req, photos, validateParsed(&req, fields)
 }
 
 // UpdateFeedback updates existing feedback
-func UpdateFeedback(c *gin.Context) {
-    id := c.Param("id")
+func (fc *FeedbackController) UpdateFeedback(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
-    var feedback models.Feedback
-    if err := config.DB.First(&feedback, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Feedback not found"})
+    feedback, err := fc.feedback.Find(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
+        return
+    }
+    if _, err := activeUser(fc.users, feedback.UserID); err != nil {
+        c.Error(err)
         return
     }
     
-    var updateData models.Feedback
-    if err := c.ShouldBindJSON(&updateData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
+    var req FeedbackUpdateRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
         return
     }
     
-    // Validate rating if provided
-    if updateData.Rating != 0 && !updateData.IsValidRating() {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
+    updateData := models.Feedback{
+        Comment: req.Comment,
+        Rating:  req.Rating,
+    }
+    comment := feedback.Comment
+    if updateData.Comment != "" {
+        comment = updateData.Comment
+    }
+    updateData.Status, updateData.ModerationNote = fc.filter.Status(feedback.Status, feedback.ModerationNote, comment)
+    
+    if err := fc.feedback.Update(&feedback, updateData); err != nil {
+        c.Error(apperrors.Internal("Error updating feedback", err))
         return
     }
     
-    if err := config.DB.Model(&feedback).Updates(updateData).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating feedback"})
+    c.JSON(http.StatusOK, feedback)
+}
+
+// DeleteFeedback moves feedback to the trash
+func (fc *FeedbackController) DeleteFeedback(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
         return
     }
     
-    // Load relationships for response
-    config.DB.Preload("User").Preload("Recipe").First(&feedback, feedback.ID)
+    feedback, err := fc.feedback.Find(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
+        return
+    }
     
-    c.JSON(http.StatusOK, feedback)
+    // Deleted reviews go to the trash and can be restored until they are purged
+    if err := fc.feedback.Delete(&feedback, models.TrashTime(time.Now())); err != nil {
+        c.Error(apperrors.Internal("Error deleting feedback", err))
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{
+        "message":          "Feedback deleted successfully",
+        "restorable_until": models.RestorableUntil(feedback.DeletedAt),
+    })
+}
+
+// AddFeedbackReply adds a reply to a review's thread, optionally answering
+// another reply (parent_id) up to models.MaxReplyDepth levels deep
+func (fc *FeedbackController) AddFeedbackReply(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    
+    feedback, err := fc.feedback.FindApproved(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
+        return
+    }
+    
+    var req FeedbackReplyRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+    
+    // For now, use a default user ID (in a real app, this would come from authentication)
+    if req.UserID == 0 {
+        req.UserID = 1
+    }
+    
+    if _, err := activeUser(fc.users, req.UserID); err != nil {
+        c.Error(err)
+        return
+    }
+    
+    reply := models.FeedbackReply{
+        FeedbackID: feedback.ID,
+        UserID:     req.UserID,
+        Comment:    req.Comment,
+        Depth:      1,
+    }
+    if req.ParentID != nil {
+        parent, err := fc.feedback.FindReply(feedback.ID, *req.ParentID)
+        if errors.Is(err, repository.ErrNotFound) {
+            respondValidationError(c, []FieldError{{Field: "parent_id", Message: "must be a reply on this review"}})
+            return
+        }
+        if err != nil {
+            c.Error(apperrors.Internal("Error retrieving reply", err))
+            return
+        }
+        if parent.Depth >= models.MaxReplyDepth {
+            respondValidationError(c, []FieldError{{Field: "parent_id", Message: fmt.Sprintf("replies cannot be nested more than %d levels deep", models.MaxReplyDepth)}})
+            return
+        }
+        reply.ParentID = &parent.ID
+        reply.Depth = parent.Depth + 1
+    }
+    
+    reply.Status, reply.ModerationNote = fc.filter.Status("", "", req.Comment)
+    
+    if err := fc.feedback.AddReply(&reply); err != nil {
+        c.Error(apperrors.Internal("Error saving reply", err))
+        return
+    }
+    
+    // The review's recipe is live, so it can only fail to load if the database does
+    if recipe, err := fc.recipes.Find(feedback.RecipeID); err == nil {
+        reply.IsAuthor = reply.UserID == recipe.UserID
+    }
+    
+    c.JSON(http.StatusCreated, reply)
 }
 
-// DeleteFeedback deletes feedback
-func DeleteFeedback(c *gin.Context) {
-    id := c.Param("id")
+// VoteFeedback records whether a user found another user's review helpful;
+// voting again changes the vote
+func (fc *FeedbackController) VoteFeedback(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
-    var feedback models.Feedback
-    if err := config.DB.First(&feedback, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Feedback not found"})
+    feedback, err := fc.feedback.FindApproved(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
         return
     }
     
-    if err := config.DB.Delete(&feedback).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting feedback"})
+    var req FeedbackVoteRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
         return
     }
     
-    c.JSON(http.StatusOK, gin.H{"message": "Feedback deleted successfully"})
+    // For now, use a default user ID (in a real app, this would come from authentication)
+    if req.UserID == 0 {
+        req.UserID = 1
+    }
+    
+    if _, err := activeUser(fc.users, req.UserID); err != nil {
+        c.Error(err)
+        return
+    }
+    if req.UserID == feedback.UserID {
+        c.Error(apperrors.Forbidden("You cannot vote on your own review"))
+        return
+    }
+    
+    if err := fc.feedback.Vote(&feedback, req.UserID, *req.Helpful); err != nil {
+        c.Error(apperrors.Internal("Error saving vote", err))
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{
+        "feedback_id":     feedback.ID,
+        "helpful":         *req.Helpful,
+        "helpful_count":   feedback.HelpfulCount,
+        "unhelpful_count": feedback.UnhelpfulCount,
+    })
 }
 
-// GetTopRatedRecipes fetches recipes with highest average ratings
-func GetTopRatedRecipes(c *gin.Context) {
-    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+// RetractFeedbackVote removes a user's vote on a review (?user_id=)
+func (fc *FeedbackController) RetractFeedbackVote(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
-    var recipes []models.Recipe
+    feedback, err := fc.feedback.Find(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
+        return
+    }
     
-    // Get recipes with their average ratings
-    if err := config.DB.Preload("User").
-        Select("recipes.*, AVG(feedbacks.rating) as average_rating").
-        Joins("LEFT JOIN feedbacks ON recipes.id = feedbacks.recipe_id").
-        Group("recipes.id").
-        Having("COUNT(feedbacks.id) > 0").
-        Order("average_rating DESC").
-        Limit(limit).
-        Find(&recipes).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving top rated recipes"})
+    userParam, ok := c.GetQuery("user_id")
+    if !ok {
+        respondValidationError(c, []FieldError{{Field: "user_id", Message: "is required"}})
+        return
+    }
+    userID, err := strconv.ParseUint(userParam, 10, 64)
+    if err != nil || userID == 0 {
+        respondValidationError(c, []FieldError{{Field: "user_id", Message: "must be a number"}})
+        return
+    }
+    
+    if _, err := activeUser(fc.users, uint(userID)); err != nil {
+        c.Error(err)
+        return
+    }
+    if err := fc.feedback.RetractVote(&feedback, uint(userID)); err != nil {
+        c.Error(apperrors.Internal("Error removing vote", err))
         return
     }
     
-    // Calculate average ratings for response
-    for i := range recipes {
-        var avgRating float64
-        config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipes[i].ID).Select("AVG(rating)").Scan(&avgRating)
-        recipes[i].AverageRating = avgRating
+    c.JSON(http.StatusOK, gin.H{
+        "feedback_id":     feedback.ID,
+        "helpful_count":   feedback.HelpfulCount,
+        "unhelpful_count": feedback.UnhelpfulCount,
+    })
+}
+
+// GetRecipePhotos fetches the community's photos of a recipe, newest first
+func (fc *FeedbackController) GetRecipePhotos(c *gin.Context) {
+    id, err := paramID(c, "id", "Recipe")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    
+    recipe, err := fc.recipes.FindApproved(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
+        return
+    }
+    
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    
+    photos, pageInfo, err := fc.feedback.Photos(recipe.ID, pageReq)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving photos"))
+        return
     }
+    linkPage(c, &pageInfo)
     
     c.JSON(http.StatusOK, gin.H{
-        "recipes": recipes,
-        "limit":   limit,
+        "photos":     photos,
+        "pagination": pageInfo,
     })
 }
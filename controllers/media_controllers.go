@@ -0,0 +1,69 @@
+package controllers
+
+import (
+    "errors"
+    "io"
+    "mime"
+    "net/http"
+    "path"
+    "strings"
+    "time"
+    "shei-deli/apperrors"
+    "shei-deli/storage"
+    "github.com/gin-gonic/gin"
+)
+
+// MediaController serves uploaded media
+type MediaController struct {
+    store storage.BlobStore
+}
+
+// NewMediaController builds a MediaController from s
+func NewMediaController(s Services) *MediaController {
+    return &MediaController{store: s.Media}
+}
+
+// signedMediaTTL is how long redirects to remote storage stay valid
+const signedMediaTTL = 15 * time.Minute
+
+// ServeMedia serves uploaded media from the configured store. Local stores are
+// streamed; remote stores redirect to a short-lived signed URL.
+func (mc *MediaController) ServeMedia(c *gin.Context) {
+    key := strings.TrimPrefix(c.Param("key"), "/")
+    if storage.ValidateKey(key) != nil {
+        c.Error(apperrors.NotFound("Media"))
+        return
+    }
+
+    if storage.Remote(mc.store) {
+        url, err := mc.store.SignedURL(key, signedMediaTTL)
+        if err != nil {
+            c.Error(apperrors.Internal("Error signing media URL", err))
+            return
+        }
+        c.Redirect(http.StatusFound, url)
+        return
+    }
+
+    blob, err := mc.store.Get(c.Request.Context(), key)
+    if errors.Is(err, storage.ErrNotFound) {
+        c.Error(apperrors.NotFound("Media"))
+        return
+    }
+    if err != nil {
+        c.Error(apperrors.Internal("Error reading media", err))
+        return
+    }
+    defer blob.Close()
+
+    contentType := mime.TypeByExtension(path.Ext(key))
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+    // Keys are random and never reused, so the content never changes
+    c.Header("Cache-Control", "public, max-age=31536000, immutable")
+    c.Header("X-Content-Type-Options", "nosniff")
+    c.Header("Content-Type", contentType)
+    c.Status(http.StatusOK)
+    io.Copy(c.Writer, blob)
+}
@@ -0,0 +1,476 @@
+package controllers
+
+import (
+    "log"
+    "net/http"
+    "strings"
+    "shei-deli/apperrors"
+    "shei-deli/i18n"
+    "shei-deli/middleware"
+    "shei-deli/models"
+    "shei-deli/moderation"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+)
+
+// ModerationController serves content reports, the moderation queues and the dashboard
+type ModerationController struct {
+    recipes  repository.RecipeRepository
+    feedback repository.FeedbackRepository
+    users    repository.UserRepository
+    reports  repository.ReportRepository
+}
+
+// NewModerationController builds a ModerationController from s
+func NewModerationController(s Services) *ModerationController {
+    return &ModerationController{recipes: s.Recipes, feedback: s.Feedback, users: s.Users, reports: s.Reports}
+}
+
+// ReportRequest represents a user's report of a recipe or review
+type ReportRequest struct {
+    UserID  uint   `json:"user_id"`
+    Reason  string `json:"reason" binding:"required,oneof=spam offensive inappropriate other"`
+    Details string `json:"details" binding:"max=1000"`
+}
+
+// ModerationDecision represents a moderator approving or rejecting queued content
+type ModerationDecision struct {
+    Status string `json:"status" binding:"required,oneof=approved rejected"`
+    Note   string `json:"note" binding:"max=500"`
+}
+
+// screenRecipe runs filter over a recipe's text and sets its status and note
+func screenRecipe(filter *moderation.Filter, recipe *models.Recipe, prev models.ModerationStatus) {
+    recipe.Status, recipe.ModerationNote = filter.Status(prev, recipe.ModerationNote, recipe.Title, recipe.Description, recipe.Ingredients, recipe.Instructions)
+}
+
+// ReportRecipe lets a user report a recipe to the moderators
+func (mc *ModerationController) ReportRecipe(c *gin.Context) {
+    id, err := paramID(c, "id", "Recipe")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    recipe, err := mc.recipes.FindApproved(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
+        return
+    }
+    mc.fileReport(c, models.ContentRecipe, recipe.ID)
+}
+
+// ReportFeedback lets a user report a review to the moderators
+func (mc *ModerationController) ReportFeedback(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    feedback, err := mc.feedback.FindApproved(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
+        return
+    }
+    mc.fileReport(c, models.ContentFeedback, feedback.ID)
+}
+
+// ReportReply lets a user report a reply in a review's thread to the moderators
+func (mc *ModerationController) ReportReply(c *gin.Context) {
+    id, err := paramID(c, "id", "Feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    replyID, err := paramID(c, "replyId", "Reply")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    feedback, err := mc.feedback.FindApproved(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Feedback"))
+        return
+    }
+    reply, err := mc.feedback.FindReply(feedback.ID, replyID)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Reply"))
+        return
+    }
+    mc.fileReport(c, models.ContentReply, reply.ID)
+}
+
+// fileReport records a report and hides the content once it has
+// models.FlagThreshold open reports
+func (mc *ModerationController) fileReport(c *gin.Context, contentType string, contentID uint) {
+    var req ReportRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    // For now, use a default user ID (in a real app, this would come from authentication)
+    if req.UserID == 0 {
+        req.UserID = 1
+    }
+
+    if _, err := activeUser(mc.users, req.UserID); err != nil {
+        c.Error(err)
+        return
+    }
+
+    filed, err := mc.reports.Filed(contentType, contentID, req.UserID)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving reports", err))
+        return
+    }
+    if filed {
+        c.Error(apperrors.Conflict("You have already reported this " + contentType))
+        return
+    }
+
+    report := models.Report{
+        ContentType: contentType,
+        ContentID:   contentID,
+        UserID:      req.UserID,
+        Reason:      req.Reason,
+        Details:     req.Details,
+    }
+    status, err := mc.reports.File(&report)
+    if err != nil {
+        c.Error(apperrors.Internal("Error saving report", err))
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "message": "Thanks, a moderator will review this " + contentType,
+        "report":  report,
+        "status":  status,
+    })
+}
+
+// parseStatusFilter reads ?status= as a comma-separated list of moderation
+// statuses, defaulting to the queue of pending and flagged content
+func parseStatusFilter(c *gin.Context) ([]models.ModerationStatus, error) {
+    var statuses []models.ModerationStatus
+    for _, value := range strings.Split(c.DefaultQuery("status", "pending,flagged"), ",") {
+        value = strings.TrimSpace(value)
+        if !models.IsValidModerationStatus(value) {
+            return nil, apperrors.Validation([]FieldError{{Field: "status", Message: "must be a comma-separated list of: pending, approved, rejected, flagged"}})
+        }
+        statuses = append(statuses, models.ModerationStatus(value))
+    }
+    return statuses, nil
+}
+
+// GetRecipeQueue lists recipes in the given moderation statuses, oldest first,
+// with their open reports
+func (mc *ModerationController) GetRecipeQueue(c *gin.Context) {
+    statuses, err := parseStatusFilter(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    recipes, pageInfo, err := mc.recipes.Queue(statuses, pageReq)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving moderation queue"))
+        return
+    }
+    linkPage(c, &pageInfo)
+
+    ids := make([]uint, len(recipes))
+    for i, r := range recipes {
+        ids[i] = r.ID
+    }
+    reports, err := mc.reports.Open(models.ContentRecipe, ids)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving reports", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "recipes":    recipes,
+        "reports":    reports,
+        "pagination": pageInfo,
+    })
+}
+
+// GetFeedbackQueue lists reviews in the given moderation statuses, oldest
+// first, with their open reports
+func (mc *ModerationController) GetFeedbackQueue(c *gin.Context) {
+    statuses, err := parseStatusFilter(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    feedbacks, pageInfo, err := mc.feedback.Queue(statuses, pageReq)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving moderation queue"))
+        return
+    }
+    linkPage(c, &pageInfo)
+
+    ids := make([]uint, len(feedbacks))
+    for i, f := range feedbacks {
+        ids[i] = f.ID
+    }
+    reports, err := mc.reports.Open(models.ContentFeedback, ids)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving reports", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "feedbacks":  feedbacks,
+        "reports":    reports,
+        "pagination": pageInfo,
+    })
+}
+
+// GetReplyQueue lists replies in the given moderation statuses, oldest
+// first, with their open reports
+func (mc *ModerationController) GetReplyQueue(c *gin.Context) {
+    statuses, err := parseStatusFilter(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    replies, pageInfo, err := mc.feedback.ReplyQueue(statuses, pageReq)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving moderation queue"))
+        return
+    }
+    linkPage(c, &pageInfo)
+
+    ids := make([]uint, len(replies))
+    for i, r := range replies {
+        ids[i] = r.ID
+    }
+    reports, err := mc.reports.Open(models.ContentReply, ids)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving reports", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "replies":    replies,
+        "reports":    reports,
+        "pagination": pageInfo,
+    })
+}
+
+// ModerateRecipe approves or rejects a recipe and closes its reports
+func (mc *ModerationController) ModerateRecipe(c *gin.Context) {
+    var req ModerationDecision
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    recipe, err := mc.moderateRecipe(c, c.Param("id"), models.ModerationStatus(req.Status), req.Note)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.JSON(http.StatusOK, recipe)
+}
+
+// ModerateFeedback approves or rejects a review and closes its reports
+func (mc *ModerationController) ModerateFeedback(c *gin.Context) {
+    var req ModerationDecision
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    feedback, err := mc.moderateFeedback(c, c.Param("id"), models.ModerationStatus(req.Status), req.Note)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.JSON(http.StatusOK, feedback)
+}
+
+// ModerateReply approves or rejects a reply and closes its reports
+func (mc *ModerationController) ModerateReply(c *gin.Context) {
+    var req ModerationDecision
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    reply, err := mc.moderateReply(c, c.Param("id"), models.ModerationStatus(req.Status), req.Note)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    c.JSON(http.StatusOK, reply)
+}
+
+// moderateRecipe applies a moderator's decision to a recipe
+func (mc *ModerationController) moderateRecipe(c *gin.Context, param string, status models.ModerationStatus, note string) (models.Recipe, *apperrors.Error) {
+    id, appErr := parseID(param, "Recipe")
+    if appErr != nil {
+        return models.Recipe{}, appErr
+    }
+    recipe, err := mc.recipes.Find(id)
+    if err != nil {
+        return recipe, apperrors.FromDB(err, "Recipe")
+    }
+
+    if err := mc.recipes.Moderate(&recipe, status, note); err != nil {
+        return recipe, apperrors.Internal("Error saving moderation decision", err)
+    }
+    logDecision(c, models.ContentRecipe, recipe.ID, status)
+    return recipe, nil
+}
+
+// moderateFeedback applies a moderator's decision to a review, adding it to
+// or removing it from its recipe's rating
+func (mc *ModerationController) moderateFeedback(c *gin.Context, param string, status models.ModerationStatus, note string) (models.Feedback, *apperrors.Error) {
+    id, appErr := parseID(param, "Feedback")
+    if appErr != nil {
+        return models.Feedback{}, appErr
+    }
+    feedback, err := mc.feedback.Find(id)
+    if err != nil {
+        return feedback, apperrors.FromDB(err, "Feedback")
+    }
+
+    if err := mc.feedback.Moderate(&feedback, status, note); err != nil {
+        return feedback, apperrors.Internal("Error saving moderation decision", err)
+    }
+    logDecision(c, models.ContentFeedback, feedback.ID, status)
+    return feedback, nil
+}
+
+// moderateReply applies a moderator's decision to a reply
+func (mc *ModerationController) moderateReply(c *gin.Context, param string, status models.ModerationStatus, note string) (models.FeedbackReply, *apperrors.Error) {
+    id, appErr := parseID(param, "Reply")
+    if appErr != nil {
+        return models.FeedbackReply{}, appErr
+    }
+    reply, err := mc.feedback.Reply(id)
+    if err != nil {
+        return reply, apperrors.FromDB(err, "Reply")
+    }
+
+    if err := mc.feedback.ModerateReply(&reply, status, note); err != nil {
+        return reply, apperrors.Internal("Error saving moderation decision", err)
+    }
+    logDecision(c, models.ContentReply, reply.ID, status)
+    return reply, nil
+}
+
+// logDecision records which moderator made a decision
+func logDecision(c *gin.Context, contentType string, id uint, status models.ModerationStatus) {
+    moderator, _ := middleware.GetModerator(c)
+    log.Printf("[%s] moderator %q set %s %d to %s", middleware.GetRequestID(c), moderator.Username, contentType, id, status)
+}
+
+// moderationActions maps dashboard form actions to moderation statuses
+var moderationActions = map[string]models.ModerationStatus{
+    "approve": models.StatusApproved,
+    "reject":  models.StatusRejected,
+}
+
+// ModerationDashboardHandler serves the moderation queue page
+func (mc *ModerationController) ModerationDashboardHandler(c *gin.Context) {
+    statuses := []models.ModerationStatus{models.StatusPending, models.StatusFlagged}
+    firstPage := PageRequest{Limit: 50, Page: 1}
+
+    var feedbacks []models.Feedback
+    var replies []models.FeedbackReply
+    recipeReports := map[uint][]models.Report{}
+    feedbackReports := map[uint][]models.Report{}
+    replyReports := map[uint][]models.Report{}
+    recipes, _, err := mc.recipes.Queue(statuses, firstPage)
+    if err == nil {
+        feedbacks, _, err = mc.feedback.Queue(statuses, firstPage)
+    }
+    if err == nil {
+        replies, _, err = mc.feedback.ReplyQueue(statuses, firstPage)
+    }
+    if err == nil {
+        ids := make([]uint, len(recipes))
+        for i, r := range recipes {
+            ids[i] = r.ID
+        }
+        recipeReports, err = mc.reports.Open(models.ContentRecipe, ids)
+    }
+    if err == nil {
+        ids := make([]uint, len(feedbacks))
+        for i, f := range feedbacks {
+            ids[i] = f.ID
+        }
+        feedbackReports, err = mc.reports.Open(models.ContentFeedback, ids)
+    }
+    if err == nil {
+        ids := make([]uint, len(replies))
+        for i, r := range replies {
+            ids[i] = r.ID
+        }
+        replyReports, err = mc.reports.Open(models.ContentReply, ids)
+    }
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_moderation")
+        return
+    }
+
+    moderator, _ := middleware.GetModerator(c)
+    renderHTML(c, http.StatusOK, "moderation.html", gin.H{
+        "Title":           i18n.T(middleware.GetLocale(c), "page.moderation"),
+        "Moderator":       moderator,
+        "Recipes":         recipes,
+        "Feedbacks":       feedbacks,
+        "RecipeReports":   recipeReports,
+        "FeedbackReports": feedbackReports,
+        "Replies":         replies,
+        "ReplyReports":    replyReports,
+    })
+}
+
+// ModerationActionHandler applies an approve or reject button from the
+// dashboard and returns to the queue
+func (mc *ModerationController) ModerationActionHandler(c *gin.Context) {
+    status, ok := moderationActions[c.Param("action")]
+    if !ok {
+        renderError(c, http.StatusNotFound, "page.not_found", "error.unknown_moderation_action")
+        return
+    }
+
+    var err *apperrors.Error
+    switch c.Param("type") {
+    case "recipes":
+        _, err = mc.moderateRecipe(c, c.Param("id"), status, c.PostForm("note"))
+    case "feedback":
+        _, err = mc.moderateFeedback(c, c.Param("id"), status, c.PostForm("note"))
+    case "replies":
+        _, err = mc.moderateReply(c, c.Param("id"), status, c.PostForm("note"))
+    default:
+        err = apperrors.NotFound("Content type")
+    }
+    if err != nil {
+        renderError(c, err.Status, "page.moderation_failed", "error.moderation_failed")
+        return
+    }
+
+    c.Redirect(http.StatusSeeOther, "/moderation")
+}
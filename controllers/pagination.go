@@ -0,0 +1,127 @@
+package controllers
+
+import (
+    "errors"
+    "fmt"
+    "net/url"
+    "strconv"
+    "strings"
+    "shei-deli/apperrors"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    defaultPageLimit = 10
+    maxPageLimit     = 100
+)
+
+// PageRequest and PageInfo are the repository's pagination types; list
+// handlers parse the one and add links to the other
+type (
+    PageRequest = repository.PageRequest
+    PageInfo    = repository.PageInfo
+)
+
+// parsePageRequest reads and validates pagination query parameters
+func parsePageRequest(c *gin.Context) (PageRequest, error) {
+    req := PageRequest{Limit: defaultPageLimit, Page: 1}
+    var fields []FieldError
+
+    if value := c.Query("limit"); value != "" {
+        limit, err := strconv.Atoi(value)
+        if err != nil || limit < 1 || limit > maxPageLimit {
+            fields = append(fields, FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxPageLimit)})
+        } else {
+            req.Limit = limit
+        }
+    }
+
+    if value, ok := c.GetQuery("cursor"); ok {
+        req.CursorMode = true
+        if value != "" {
+            cursor, err := repository.DecodeCursor(value)
+            if err != nil {
+                fields = append(fields, FieldError{Field: "cursor", Message: "is invalid"})
+            }
+            req.Cursor = cursor
+        }
+    } else if value := c.Query("page"); value != "" {
+        page, err := strconv.Atoi(value)
+        if err != nil || page < 1 {
+            fields = append(fields, FieldError{Field: "page", Message: "must be at least 1"})
+        } else {
+            req.Page = page
+        }
+    }
+
+    if len(fields) > 0 {
+        return req, apperrors.Validation(fields)
+    }
+    return req, nil
+}
+
+// linkPage fills in a page's next and prev links and sets the Link header
+func linkPage(c *gin.Context, info *PageInfo) {
+    if info.Mode == "offset" {
+        if info.Page < info.TotalPages {
+            info.Next = pageURL(c, map[string]string{"page": strconv.Itoa(info.Page + 1)})
+        }
+        if info.Page > 1 {
+            info.Prev = pageURL(c, map[string]string{"page": strconv.Itoa(min(info.Page-1, max(info.TotalPages, 1)))})
+        }
+        setLinkHeader(c, *info, map[string]string{
+            "first": pageURL(c, map[string]string{"page": "1"}),
+            "last":  pageURL(c, map[string]string{"page": strconv.Itoa(max(info.TotalPages, 1))}),
+        })
+        return
+    }
+
+    if info.NextCursor != "" {
+        info.Next = pageURL(c, map[string]string{"cursor": info.NextCursor})
+    }
+    if info.PrevCursor != "" {
+        info.Prev = pageURL(c, map[string]string{"cursor": info.PrevCursor})
+    }
+    setLinkHeader(c, *info, map[string]string{
+        "first": pageURL(c, map[string]string{"cursor": ""}),
+    })
+}
+
+// listError converts an error from loading a page into an API error
+func listError(err error, message string) error {
+    if errors.Is(err, repository.ErrCursorSort) {
+        return apperrors.Validation([]FieldError{{Field: "cursor", Message: "does not match the requested sort"}})
+    }
+    return apperrors.Internal(message, err)
+}
+
+// pageURL returns the current request URL with the given query parameters replaced
+func pageURL(c *gin.Context, params map[string]string) string {
+    q := c.Request.URL.Query()
+    delete(q, "page")
+    delete(q, "cursor")
+    for k, v := range params {
+        q.Set(k, v)
+    }
+    return (&url.URL{Path: c.Request.URL.Path, RawQuery: q.Encode()}).String()
+}
+
+// setLinkHeader writes an RFC 8288 Link header for the page
+func setLinkHeader(c *gin.Context, info PageInfo, extra map[string]string) {
+    var links []string
+    if info.Next != "" {
+        links = append(links, fmt.Sprintf(`<%s>; rel="next"`, info.Next))
+    }
+    if info.Prev != "" {
+        links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, info.Prev))
+    }
+    for _, rel := range []string{"first", "last"} {
+        if link, ok := extra[rel]; ok {
+            links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link, rel))
+        }
+    }
+    if len(links) > 0 {
+        c.Header("Link", strings.Join(links, ", "))
+    }
+}
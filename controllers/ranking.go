@@ -0,0 +1,85 @@
+package controllers
+
+import (
+    "strconv"
+    "shei-deli/apperrors"
+    "shei-deli/models"
+    "shei-deli/ranking"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+)
+
+// parseRankingParams builds ranking parameters from the method default, the
+// site-wide average rating as the Bayesian prior, and any query overrides
+// (?method=, ?prior_mean=, ?prior_weight=, ?confidence_z=, ?half_life_days=)
+func parseRankingParams(c *gin.Context, recipes repository.RecipeRepository, defaultMethod ranking.Method) (ranking.Params, error) {
+    params := ranking.DefaultParams(ranking.Method(c.DefaultQuery("method", string(defaultMethod))))
+    // The default prior stands in until something has been rated
+    avg, ok, err := recipes.AverageRating()
+    if err != nil {
+        return params, apperrors.Internal("Error computing the site average rating", err)
+    }
+    if ok {
+        params.PriorMean = avg
+    }
+
+    var fields []FieldError
+    overrides := []struct {
+        name string
+        dst  *float64
+    }{
+        {"prior_mean", &params.PriorMean},
+        {"prior_weight", &params.PriorWeight},
+        {"confidence_z", &params.ConfidenceZ},
+        {"half_life_days", &params.HalfLifeDays},
+    }
+    for _, o := range overrides {
+        value := c.Query(o.name)
+        if value == "" {
+            continue
+        }
+        f, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+            fields = append(fields, FieldError{Field: o.name, Message: "must be a number"})
+            continue
+        }
+        *o.dst = f
+    }
+    if len(fields) > 0 {
+        return params, apperrors.Validation(fields)
+    }
+
+    if err := params.Validate(); err != nil {
+        return params, apperrors.BadRequest(err.Error())
+    }
+    return params, nil
+}
+
+// defaultTrendingWindow is used when ?window= is not given
+const defaultTrendingWindow = ranking.Week
+
+// parseTrendingWindow reads ?window= (day, week or month)
+func parseTrendingWindow(c *gin.Context) (ranking.Window, error) {
+    window, err := ranking.ParseWindow(c.DefaultQuery("window", string(defaultTrendingWindow)))
+    if err != nil {
+        return defaultTrendingWindow, apperrors.Validation([]FieldError{{Field: "window", Message: "must be one of: day, week, month"}})
+    }
+    return window, nil
+}
+
+// trendingParams returns the parameters the trending refresher breaks ties
+// with, so featured listings can report how they were ordered
+func trendingParams(recipes repository.RecipeRepository) (ranking.Params, error) {
+    avg, ok, err := recipes.AverageRating()
+    if err != nil {
+        return ranking.Params{}, apperrors.Internal("Error computing the site average rating", err)
+    }
+    return ranking.TrendingParams(avg, ok), nil
+}
+
+// featuredRecipes loads one page of recipes in their materialized trending
+// order for the window
+func featuredRecipes(recipes repository.RecipeRepository, window ranking.Window, pageReq PageRequest) ([]models.Recipe, PageInfo, error) {
+    sortOpt := repository.SortOption{Key: "trending_" + string(window), Expr: "recipes." + window.Column(), Desc: true}
+    return recipes.List(repository.RecipeFilter{}, pageReq, sortOpt)
+}
@@ -1,108 +1,158 @@
 package controllers
 
 import (
-    "database/sql"
     "encoding/json"
     "fmt"
-    "io"
     "io/ioutil"
     "log"
     "net/http"
-    "os"
-    "path/filepath"
     "strconv"
     "strings"
     "time"
+    "shei-deli/apperrors"
     "shei-deli/models"
-    "shei-deli/config"
+    "shei-deli/i18n"
+    "shei-deli/images"
+    "shei-deli/middleware"
+    "shei-deli/moderation"
+    "shei-deli/ranking"
+    "shei-deli/repository"
     "github.com/gin-gonic/gin"
 )
 
-// GetRecipes fetches all recipes from database with optional category filtering
-func GetRecipes(c *gin.Context) {
-    var recipes []models.Recipe
-    query := config.DB.Preload("User").Preload("Feedbacks")
+// RecipeController serves the recipe API
+type RecipeController struct {
+    recipes  repository.RecipeRepository
+    users    repository.UserRepository
+    taxonomy repository.TaxonomyRepository
+    uploads  uploads
+    filter   *moderation.Filter
+}
 
-    // Filter by category if provided
-    category := c.Query("category")
-    if category != "" {
-        if !models.IsValidCategory(category) {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
-            return
-        }
-        query = query.Where("category = ?", category)
-    }
+// NewRecipeController builds a RecipeController from s
+func NewRecipeController(s Services) *RecipeController {
+    return &RecipeController{recipes: s.Recipes, users: s.Users, taxonomy: s.Taxonomy, uploads: newUploads(s.Media), filter: s.Filter}
+}
 
-    // Add pagination
-    page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-    offset := (page - 1) * limit
+// GetRecipes fetches all recipes from database with optional category and cuisine filtering
+func (rc *RecipeController) GetRecipes(c *gin.Context) {
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    sortOpt, err := parseRecipeSort(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
 
-    if err := query.Offset(offset).Limit(limit).Find(&recipes).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving recipes from the database"})
+    filter, err := rc.recipeFilter(c, c.Query("category"))
+    if err != nil {
+        c.Error(err)
         return
     }
 
-    // Calculate average ratings for each recipe
-    for i := range recipes {
-        var avgRating sql.NullFloat64
-        config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipes[i].ID).Select("AVG(rating)").Scan(&avgRating)
-        if avgRating.Valid {
-            recipes[i].AverageRating = avgRating.Float64
-        } else {
-            recipes[i].AverageRating = 0.0
-        }
+    recipes, pageInfo, err := rc.recipes.List(filter, pageReq, sortOpt)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving recipes from the database"))
+        return
+    }
+    linkPage(c, &pageInfo)
+    if err := localizeRecipes(c, rc.recipes, recipes); err != nil {
+        c.Error(err)
+        return
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "recipes": recipes,
-        "page":    page,
-        "limit":   limit,
+        "recipes":    recipes,
+        "sort":       sortOpt.Key,
+        "pagination": pageInfo,
     })
 }
 
-// GetRecipesByCategory fetches recipes by specific category
-func GetRecipesByCategory(c *gin.Context) {
+// GetRecipesByCategory fetches recipes in a category and its subcategories,
+// optionally filtered by ?cuisine=
+func (rc *RecipeController) GetRecipesByCategory(c *gin.Context) {
     category := c.Param("category")
 
-    if !models.IsValidCategory(category) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
         return
     }
-
-    var recipes []models.Recipe
-    if err := config.DB.Preload("User").Preload("Feedbacks").Where("category = ?", category).Find(&recipes).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving recipes"})
+    sortOpt, err := parseRecipeSort(c)
+    if err != nil {
+        c.Error(err)
         return
     }
 
-    // Calculate average ratings
-    for i := range recipes {
-        var avgRating float64
-        config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipes[i].ID).Select("AVG(rating)").Scan(&avgRating)
-        recipes[i].AverageRating = avgRating
+    filter, err := rc.recipeFilter(c, category)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    recipes, pageInfo, err := rc.recipes.List(filter, pageReq, sortOpt)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving recipes"))
+        return
+    }
+    linkPage(c, &pageInfo)
+    if err := localizeRecipes(c, rc.recipes, recipes); err != nil {
+        c.Error(err)
+        return
     }
 
     c.JSON(http.StatusOK, gin.H{
-        "category": models.RecipeCategory(category).GetDisplayName(),
-        "recipes":  recipes,
+        "category":   rc.taxonomy.CategoryName(models.RecipeCategory(category), middleware.GetLocale(c)),
+        "recipes":    recipes,
+        "sort":       sortOpt.Key,
+        "pagination": pageInfo,
     })
 }
 
-// GetRecipeByID fetches a single recipe by ID
-func GetRecipeByID(c *gin.Context) {
-    id := c.Param("id")
+// recipeFilter narrows a recipe listing to a category and all of its
+// subcategories, and to the ?cuisine= query parameter when one is given
+func (rc *RecipeController) recipeFilter(c *gin.Context, category string) (repository.RecipeFilter, error) {
+    var filter repository.RecipeFilter
+    if category != "" {
+        if !rc.taxonomy.IsCategory(category) {
+            return filter, apperrors.BadRequest("Invalid category")
+        }
+        keys, err := rc.taxonomy.CategoryKeys(category)
+        if err != nil {
+            return filter, apperrors.Internal("Error retrieving subcategories", err)
+        }
+        filter.Categories = keys
+    }
+
+    if cuisine := c.Query("cuisine"); cuisine != "" {
+        if !rc.taxonomy.IsCuisine(cuisine) {
+            return filter, apperrors.BadRequest("Invalid cuisine")
+        }
+        filter.Cuisine = cuisine
+    }
+    return filter, nil
+}
 
-    var recipe models.Recipe
-    if err := config.DB.Preload("User").Preload("Feedbacks.User").First(&recipe, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+// GetRecipeByID fetches a single recipe by ID, translated into the request's
+// language when its author provided a translation
+func (rc *RecipeController) GetRecipeByID(c *gin.Context) {
+    id, err := paramID(c, "id", "Recipe")
+    if err != nil {
+        c.Error(err)
         return
     }
 
-    // Calculate average rating
-    var avgRating float64
-    config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipe.ID).Select("AVG(rating)").Scan(&avgRating)
-    recipe.AverageRating = avgRating
+    recipe, err := rc.recipes.FindWithReviews(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
+        return
+    }
+    if err := localizeRecipe(c, rc.recipes, &recipe); err != nil {
+        c.Error(err)
+        return
+    }
 
     c.JSON(http.StatusOK, recipe)
 }
@@ -115,13 +165,18 @@ func GetSpoonacularRecipes(c *gin.Context) {
     spoonacularURL := fmt.Sprintf("https://api.spoonacular.com/recipes/complexSearch?query=%s&apiKey=%s", query, apiKey)
 
     resp, err := http.Get(spoonacularURL)
-    if err != nil || resp.StatusCode != http.StatusOK {
+    if err != nil {
         log.Println("Error fetching data from Spoonacular API:", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching recipes from Spoonacular"})
+        c.Error(apperrors.Upstream("Error fetching recipes from Spoonacular", err))
         return
     }
     defer resp.Body.Close()
 
+    if resp.StatusCode != http.StatusOK {
+        c.Error(apperrors.Upstream("Error fetching recipes from Spoonacular", fmt.Errorf("unexpected status %d", resp.StatusCode)))
+        return
+    }
+
     body, _ := ioutil.ReadAll(resp.Body)
     var result map[string]interface{}
     json.Unmarshal(body, &result)
@@ -130,222 +185,396 @@ func GetSpoonacularRecipes(c *gin.Context) {
 }
 
 // AddRecipe creates a new recipe in the database with optional image upload
-func AddRecipe(c *gin.Context) {
+func (rc *RecipeController) AddRecipe(c *gin.Context) {
     // Check if this is a multipart form (from web form) or JSON (from API)
     contentType := c.GetHeader("Content-Type")
 
     if strings.Contains(contentType, "multipart/form-data") {
-        handleRecipeFormUpload(c)
+        rc.handleRecipeFormUpload(c)
     } else {
-        handleRecipeJSONUpload(c)
+        rc.handleRecipeJSONUpload(c)
     }
 }
 
-// handleRecipeFormUpload handles multipart form data with file upload
-func handleRecipeFormUpload(c *gin.Context) {
-    // Parse form data
-    title := c.PostForm("title")
-    description := c.PostForm("description")
-    ingredients := c.PostForm("ingredients")
-    instructions := c.PostForm("instructions")
-    category := c.PostForm("category")
-    difficulty := c.PostForm("difficulty")
+// RecipeRequest represents the payload for creating a recipe
+type RecipeRequest struct {
+    Title        string `json:"title" form:"title" binding:"required,max=200"`
+    Description  string `json:"description" form:"description" binding:"max=1000"`
+    Ingredients  string `json:"ingredients" form:"ingredients" binding:"required,max=10000"`
+    Instructions string `json:"instructions" form:"instructions" binding:"required,max=20000"`
+    Category     string `json:"category" form:"category" binding:"required"` // Checked against the database by checkTaxonomy
+    Cuisine      string `json:"cuisine" form:"cuisine"`
+    Locale       string `json:"locale" form:"locale" binding:"omitempty,locale"` // Defaults to the language the request was served in
+    PrepTime     int    `json:"prep_time" form:"prep_time" binding:"min=0,max=1440"`
+    CookTime     int    `json:"cook_time" form:"cook_time" binding:"min=0,max=1440"`
+    Servings     int    `json:"servings" form:"servings" binding:"omitempty,min=1,max=100"`
+    Difficulty   string `json:"difficulty" form:"difficulty" binding:"omitempty,oneof=Easy Medium Hard"`
+    ImageURL     string `json:"image_url" form:"-" binding:"max=500"`
+    UserID       uint   `json:"user_id" form:"user_id"`
+}
 
-    // Parse numeric fields
-    prepTime, _ := strconv.Atoi(c.PostForm("prep_time"))
-    cookTime, _ := strconv.Atoi(c.PostForm("cook_time"))
-    servings, _ := strconv.Atoi(c.PostForm("servings"))
-    userID, _ := strconv.ParseUint(c.PostForm("user_id"), 10, 32)
+// RecipeUpdateRequest represents the payload for updating a recipe; empty fields are left unchanged
+type RecipeUpdateRequest struct {
+    Title        string `json:"title" binding:"max=200"`
+    Description  string `json:"description" binding:"max=1000"`
+    Ingredients  string `json:"ingredients" binding:"max=10000"`
+    Instructions string `json:"instructions" binding:"max=20000"`
+    Category     string `json:"category"`
+    Cuisine      string `json:"cuisine"`
+    Locale       string `json:"locale" binding:"omitempty,locale"`
+    PrepTime     int    `json:"prep_time" binding:"min=0,max=1440"`
+    CookTime     int    `json:"cook_time" binding:"min=0,max=1440"`
+    Servings     int    `json:"servings" binding:"omitempty,min=1,max=100"`
+    Difficulty   string `json:"difficulty" binding:"omitempty,oneof=Easy Medium Hard"`
+    ImageURL     string `json:"image_url" binding:"max=500"`
+}
 
-    // Validate required fields
-    if title == "" || ingredients == "" || instructions == "" || category == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Title, ingredients, instructions, and category are required"})
-        return
+// toRecipe converts the request into a recipe model
+func (r RecipeRequest) toRecipe() models.Recipe {
+    return models.Recipe{
+        Title:        r.Title,
+        Description:  r.Description,
+        Ingredients:  r.Ingredients,
+        Instructions: r.Instructions,
+        Category:     models.RecipeCategory(r.Category),
+        Cuisine:      r.Cuisine,
+        PrepTime:     r.PrepTime,
+        CookTime:     r.CookTime,
+        Servings:     r.Servings,
+        Difficulty:   r.Difficulty,
+        ImageURL:     r.ImageURL,
+        UserID:       r.UserID,
     }
+}
 
-    // Validate category
-    if !models.IsValidCategory(category) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
-        return
+// toRecipe converts the request into a recipe model suitable for Updates
+func (r RecipeUpdateRequest) toRecipe() models.Recipe {
+    return models.Recipe{
+        Title:        r.Title,
+        Description:  r.Description,
+        Ingredients:  r.Ingredients,
+        Instructions: r.Instructions,
+        Category:     models.RecipeCategory(r.Category),
+        Cuisine:      r.Cuisine,
+        PrepTime:     r.PrepTime,
+        CookTime:     r.CookTime,
+        Servings:     r.Servings,
+        Difficulty:   r.Difficulty,
+        ImageURL:     r.ImageURL,
     }
+}
 
-    // Handle image upload
-    imageURL := ""
-    file, header, err := c.Request.FormFile("image")
-    if err == nil && header != nil {
-        defer file.Close()
-
-        // Generate unique filename
-        ext := filepath.Ext(header.Filename)
-        filename := fmt.Sprintf("recipe_%d_%s%s", time.Now().Unix(), strings.ReplaceAll(title, " ", "_"), ext)
-        uploadPath := filepath.Join("static", "uploads", filename)
-
-        // Create upload directory if it doesn't exist
-        os.MkdirAll(filepath.Dir(uploadPath), 0755)
+// bindRecipeForm reads a multipart recipe form, reporting unparseable numbers per field
+func (rc *RecipeController) bindRecipeForm(c *gin.Context) (RecipeRequest, []FieldError) {
+    req := RecipeRequest{
+        Title:        c.PostForm("title"),
+        Description:  c.PostForm("description"),
+        Ingredients:  c.PostForm("ingredients"),
+        Instructions: c.PostForm("instructions"),
+        Category:     c.PostForm("category"),
+        Cuisine:      c.PostForm("cuisine"),
+        Locale:       c.PostForm("locale"),
+        Difficulty:   c.PostForm("difficulty"),
+    }
 
-        // Save file
-        dst, err := os.Create(uploadPath)
+    var fields []FieldError
+    numericFields := []struct {
+        name string
+        dst  *int
+    }{
+        {"prep_time", &req.PrepTime},
+        {"cook_time", &req.CookTime},
+        {"servings", &req.Servings},
+    }
+    for _, nf := range numericFields {
+        value := c.PostForm(nf.name)
+        if value == "" {
+            continue
+        }
+        n, err := strconv.Atoi(value)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-            return
+            fields = append(fields, FieldError{Field: nf.name, Message: "must be a whole number"})
+            continue
         }
-        defer dst.Close()
+        *nf.dst = n
+    }
 
-        if _, err := io.Copy(dst, file); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-            return
+    if value := c.PostForm("user_id"); value != "" {
+        userID, err := strconv.ParseUint(value, 10, 32)
+        if err != nil {
+            fields = append(fields, FieldError{Field: "user_id", Message: "must be a positive whole number"})
         }
+        req.UserID = uint(userID)
+    }
 
-        imageURL = "/" + uploadPath
-    } else {
-        // Use default category image if no image uploaded
-        imageURL = getCategoryDefaultImage(models.RecipeCategory(category))
+    fields = validateParsed(&req, fields)
+    return req, checkTaxonomy(rc.taxonomy, fields, req.Category, req.Cuisine)
+}
+
+// handleRecipeFormUpload handles multipart form data with file upload
+func (rc *RecipeController) handleRecipeFormUpload(c *gin.Context) {
+    req, fields := rc.bindRecipeForm(c)
+    if len(fields) > 0 {
+        respondValidationError(c, fields)
+        return
     }
 
     // Set default user ID if not provided
-    if userID == 0 {
-        userID = 1 // Default to admin user
+    if req.UserID == 0 {
+        req.UserID = 1 // Default to admin user
+    }
+    if _, err := activeUser(rc.users, req.UserID); err != nil {
+        c.Error(err)
+        return
     }
 
-    // Create recipe
-    newRecipe := models.Recipe{
-        Title:        title,
-        Description:  description,
-        Ingredients:  ingredients,
-        Instructions: instructions,
-        Category:     models.RecipeCategory(category),
-        PrepTime:     prepTime,
-        CookTime:     cookTime,
-        Servings:     servings,
-        Difficulty:   difficulty,
-        ImageURL:     imageURL,
-        UserID:       uint(userID),
+    // Handle image upload
+    var image images.Stored
+    if header, err := c.FormFile("image"); err == nil {
+        image, err = rc.uploads.save(header, "image", "recipe")
+        if err != nil {
+            c.Error(err)
+            return
+        }
+    } else {
+        // Use default category image if no image uploaded
+        image.URL = rc.taxonomy.CategoryImageURL(models.RecipeCategory(req.Category))
     }
 
-    if err := config.DB.Create(&newRecipe).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving recipe to the database"})
+    // Create recipe
+    newRecipe := req.toRecipe()
+    newRecipe.Locale = recipeLocale(c, req.Locale)
+    newRecipe.ImageURL = image.URL
+    newRecipe.CardURL = image.CardURL
+    newRecipe.ThumbnailURL = image.ThumbnailURL
+    screenRecipe(rc.filter, &newRecipe, "")
+
+    if err := rc.recipes.Create(&newRecipe); err != nil {
+        rc.uploads.remove([]string{image.URL})
+        c.Error(apperrors.Internal("Error saving recipe to the database", err))
         return
     }
 
-    // Load the user relationship for the response
-    config.DB.Preload("User").First(&newRecipe, newRecipe.ID)
-
     c.JSON(http.StatusCreated, newRecipe)
 }
 
 // handleRecipeJSONUpload handles JSON data (for API calls)
-func handleRecipeJSONUpload(c *gin.Context) {
-    var newRecipe models.Recipe
-    if err := c.ShouldBindJSON(&newRecipe); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
-        return
+func (rc *RecipeController) handleRecipeJSONUpload(c *gin.Context) {
+    var req RecipeRequest
+    var fields []FieldError
+    if err := c.ShouldBindJSON(&req); err != nil {
+        fields = fieldErrors(err)
     }
-
-    // Validate category
-    if !models.IsValidCategory(string(newRecipe.Category)) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+    if fields = checkTaxonomy(rc.taxonomy, fields, req.Category, req.Cuisine); len(fields) > 0 {
+        respondValidationError(c, fields)
         return
     }
 
-    // Validate required fields
-    if newRecipe.Title == "" || newRecipe.Ingredients == "" || newRecipe.Instructions == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Title, ingredients, and instructions are required"})
-        return
-    }
+    newRecipe := req.toRecipe()
+    newRecipe.Locale = recipeLocale(c, req.Locale)
 
     // Set default image if not provided
     if newRecipe.ImageURL == "" {
-        newRecipe.ImageURL = getCategoryDefaultImage(newRecipe.Category)
+        newRecipe.ImageURL = rc.taxonomy.CategoryImageURL(newRecipe.Category)
     }
 
     // For now, use a default user ID (in a real app, this would come from authentication)
     if newRecipe.UserID == 0 {
         newRecipe.UserID = 1 // Default to admin user
     }
-
-    if err := config.DB.Create(&newRecipe).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving recipe to the database"})
+    if _, err := activeUser(rc.users, newRecipe.UserID); err != nil {
+        c.Error(err)
         return
     }
+    screenRecipe(rc.filter, &newRecipe, "")
 
-    // Load the user relationship for the response
-    config.DB.Preload("User").First(&newRecipe, newRecipe.ID)
+    if err := rc.recipes.Create(&newRecipe); err != nil {
+        c.Error(apperrors.Internal("Error saving recipe to the database", err))
+        return
+    }
 
     c.JSON(http.StatusCreated, newRecipe)
 }
 
-// getCategoryDefaultImage returns the default image path for a category
-func getCategoryDefaultImage(category models.RecipeCategory) string {
-    categoryImages := map[models.RecipeCategory]string{
-        models.PlantBasedMeals: "/images/vegan.jpeg",
-        models.KidsMeals:       "/images/kids-meals.jpeg",
-        models.LightMeals:      "/images/light-meals.jpeg",
-        models.HeartyMeals:     "/images/hearty-meals.jpeg",
-        models.MeatStews:       "/images/stews.jpeg",
-        models.VeggieStews:     "/images/vegetable-stews.jpeg",
-        models.SeafoodStews:    "/images/fish&sea-food.jpeg",
-        models.FusionStews:     "/images/fusion.jpeg",
-        models.Soups:           "/images/soups.jpeg",
-        models.Drinks:          "/images/drinks&smoothies.jpeg",
-        models.Pastries:        "/images/pastries.jpeg",
-    }
-
-    if imagePath, exists := categoryImages[category]; exists {
-        return imagePath
+// recipeLocale returns the language a new recipe is written in: the requested
+// locale if given, otherwise the language the request was served in
+func recipeLocale(c *gin.Context, requested string) string {
+    if locale, ok := i18n.Match(requested); ok {
+        return locale
     }
-
-    return "/images/vegan.jpeg" // Default fallback
+    return middleware.GetLocale(c)
 }
 
 // UpdateRecipe updates an existing recipe
-func UpdateRecipe(c *gin.Context) {
-    id := c.Param("id")
-
-    var recipe models.Recipe
-    if err := config.DB.First(&recipe, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+func (rc *RecipeController) UpdateRecipe(c *gin.Context) {
+    id, err := paramID(c, "id", "Recipe")
+    if err != nil {
+        c.Error(err)
         return
     }
 
-    var updateData models.Recipe
-    if err := c.ShouldBindJSON(&updateData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
+    recipe, err := rc.recipes.Find(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
         return
     }
-
-    // Validate category if provided
-    if updateData.Category != "" && !models.IsValidCategory(string(updateData.Category)) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+    if _, err := activeUser(rc.users, recipe.UserID); err != nil {
+        c.Error(err)
         return
     }
 
-    if err := config.DB.Model(&recipe).Updates(updateData).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating recipe"})
+    var req RecipeUpdateRequest
+    var fields []FieldError
+    if err := c.ShouldBindJSON(&req); err != nil {
+        fields = fieldErrors(err)
+    }
+    if fields = checkTaxonomy(rc.taxonomy, fields, req.Category, req.Cuisine); len(fields) > 0 {
+        respondValidationError(c, fields)
         return
     }
+    updateData := req.toRecipe()
+    if req.Locale != "" {
+        updateData.Locale, _ = i18n.Match(req.Locale)
+    }
+
+    // Edits are screened like new submissions, over the text as it will read afterwards
+    edited := recipe
+    textFields := []struct {
+        dst   *string
+        value string
+    }{
+        {&edited.Title, updateData.Title},
+        {&edited.Description, updateData.Description},
+        {&edited.Ingredients, updateData.Ingredients},
+        {&edited.Instructions, updateData.Instructions},
+    }
+    for _, tf := range textFields {
+        if tf.value != "" {
+            *tf.dst = tf.value
+        }
+    }
+    screenRecipe(rc.filter, &edited, recipe.Status)
+    updateData.Status = edited.Status
+    updateData.ModerationNote = edited.ModerationNote
+
+    // Replacing the image orphans the old upload and its variants
+    oldImageURL := recipe.ImageURL
+    imageChanged := updateData.ImageURL != "" && updateData.ImageURL != oldImageURL
 
-    // Load relationships for response
-    config.DB.Preload("User").First(&recipe, recipe.ID)
+    if err := rc.recipes.Update(&recipe, updateData, imageChanged); err != nil {
+        c.Error(apperrors.Internal("Error updating recipe", err))
+        return
+    }
+    if imageChanged {
+        rc.uploads.remove([]string{oldImageURL})
+    }
 
     c.JSON(http.StatusOK, recipe)
 }
 
-// DeleteRecipe deletes a recipe from the database
-func DeleteRecipe(c *gin.Context) {
-    id := c.Param("id")
+// DeleteRecipe moves a recipe and its reviews to the trash
+func (rc *RecipeController) DeleteRecipe(c *gin.Context) {
+    id, err := paramID(c, "id", "Recipe")
+    if err != nil {
+        c.Error(err)
+        return
+    }
 
-    var recipe models.Recipe
-    if err := config.DB.First(&recipe, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+    recipe, err := rc.recipes.Find(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Recipe"))
         return
     }
 
-    if err := config.DB.Delete(&recipe).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting recipe"})
+    // Deleted recipes go to the trash with their reviews; the image is only
+    // removed when the purge job deletes the recipe for good
+    if err := rc.recipes.Delete(&recipe, models.TrashTime(time.Now())); err != nil {
+        c.Error(apperrors.Internal("Error deleting recipe", err))
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Recipe deleted successfully"})
+    c.JSON(http.StatusOK, gin.H{
+        "message":          "Recipe deleted successfully",
+        "restorable_until": models.RestorableUntil(recipe.DeletedAt),
+    })
 }
 
+// GetFeaturedRecipes fetches recipes in trending order for ?window= (day,
+// week or month), the JSON twin of the /featured page
+func (rc *RecipeController) GetFeaturedRecipes(c *gin.Context) {
+    window, err := parseTrendingWindow(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    params, err := trendingParams(rc.recipes)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    recipes, pageInfo, err := featuredRecipes(rc.recipes, window, pageReq)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving featured recipes"))
+        return
+    }
+    linkPage(c, &pageInfo)
+    if err := localizeRecipes(c, rc.recipes, recipes); err != nil {
+        c.Error(err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "recipes":    recipes,
+        "window":     window,
+        "ranking":    params,
+        "pagination": pageInfo,
+    })
+}
 
+// GetTopRatedRecipes fetches recipes ranked by a confidence-weighted rating so
+// a single 5-star review cannot outrank a long record of strong reviews
+func (rc *RecipeController) GetTopRatedRecipes(c *gin.Context) {
+    limit := defaultPageLimit
+    if value := c.Query("limit"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 1 || parsed > maxPageLimit {
+            respondValidationError(c, []FieldError{{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxPageLimit)}})
+            return
+        }
+        limit = parsed
+    }
+    
+    params, err := parseRankingParams(c, rc.recipes, ranking.Bayesian)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    
+    recipes, err := rc.recipes.All(repository.RecipeFilter{Rated: true}, repository.SortOption{})
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving top rated recipes", err))
+        return
+    }
+    
+    ranking.Rank(recipes, params, time.Now())
+    if len(recipes) > limit {
+        recipes = recipes[:limit]
+    }
+    if err := localizeRecipes(c, rc.recipes, recipes); err != nil {
+        c.Error(err)
+        return
+    }
+    
+    c.JSON(http.StatusOK, gin.H{
+        "recipes": recipes,
+        "limit":   limit,
+        "ranking": params,
+    })
+}
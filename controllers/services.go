@@ -0,0 +1,26 @@
+package controllers
+
+import (
+    "shei-deli/moderation"
+    "shei-deli/repository"
+    "shei-deli/storage"
+    "gorm.io/gorm"
+)
+
+// Services are the dependencies controllers are built from. routes.SetupRoutes
+// builds every controller from one set, so a test can hand it an isolated
+// database or swap a repository for a fake.
+type Services struct {
+    repository.Repositories
+    Media  storage.BlobStore  // Uploaded images
+    Filter *moderation.Filter // Screens submitted recipes and reviews
+}
+
+// NewServices returns Services whose repositories are backed by db
+func NewServices(db *gorm.DB, media storage.BlobStore, filter *moderation.Filter) Services {
+    return Services{
+        Repositories: repository.New(db),
+        Media:        media,
+        Filter:       filter,
+    }
+}
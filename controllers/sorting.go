@@ -0,0 +1,74 @@
+package controllers
+
+import (
+    "strings"
+    "shei-deli/apperrors"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+)
+
+// defaultRecipeSort is used when no ?sort= is given
+const defaultRecipeSort = "newest"
+
+// recipeSortOptions are the orderings accepted by ?sort= on recipe listings
+var recipeSortOptions = map[string]repository.SortOption{
+    "newest":       {Key: "newest", Expr: "recipes.created_at", Desc: true},
+    "rating":       {Key: "rating", Expr: "recipes.average_rating", Desc: true},
+    "rating_count": {Key: "rating_count", Expr: "recipes.rating_count", Desc: true},
+    // Times are optional; a recipe missing either sorts as if it took no time
+    "total_time":   {Key: "total_time", Expr: "(recipes.prep_time + recipes.cook_time)", NullValue: "0"},
+    "title":        {Key: "title", Expr: "LOWER(recipes.title)"},
+    // Popularity is the sum of all ratings received, rewarding both volume and quality
+    "popularity":   {Key: "popularity", Expr: "recipes.rating_sum", Desc: true},
+}
+
+// RecipeSortChoice is a sort option as presented in the UI; templates
+// translate its label from the sort.<key> message
+type RecipeSortChoice struct {
+    Key string
+}
+
+// recipeSortChoices lists the sort options in display order
+func recipeSortChoices() []RecipeSortChoice {
+    return []RecipeSortChoice{
+        {Key: "newest"},
+        {Key: "rating"},
+        {Key: "rating_count"},
+        {Key: "popularity"},
+        {Key: "total_time"},
+        {Key: "title"},
+    }
+}
+
+// parseRecipeSort reads ?sort= for recipe listings
+func parseRecipeSort(c *gin.Context) (repository.SortOption, error) {
+    key := c.DefaultQuery("sort", defaultRecipeSort)
+    option, ok := recipeSortOptions[key]
+    if !ok {
+        keys := make([]string, 0, len(recipeSortChoices()))
+        for _, choice := range recipeSortChoices() {
+            keys = append(keys, choice.Key)
+        }
+        return option, apperrors.Validation([]FieldError{{Field: "sort", Message: "must be one of: " + strings.Join(keys, ", ")}})
+    }
+    return option, nil
+}
+
+// defaultFeedbackSort is used when no ?sort= is given on review listings
+const defaultFeedbackSort = "helpful"
+
+// feedbackSortOptions are the orderings accepted by ?sort= on review listings
+var feedbackSortOptions = map[string]repository.SortOption{
+    // Net helpful votes; IDs increase with creation time so ties go to the newest review
+    "helpful": {Key: "helpful", Expr: "(feedbacks.helpful_count - feedbacks.unhelpful_count)", Desc: true},
+    "newest":  {Key: "newest", Desc: true},
+}
+
+// parseFeedbackSort reads ?sort= for review listings
+func parseFeedbackSort(c *gin.Context) (repository.SortOption, error) {
+    option, ok := feedbackSortOptions[c.DefaultQuery("sort", defaultFeedbackSort)]
+    if !ok {
+        return option, apperrors.Validation([]FieldError{{Field: "sort", Message: "must be one of: helpful, newest"}})
+    }
+    return option, nil
+}
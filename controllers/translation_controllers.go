@@ -0,0 +1,193 @@
+package controllers
+
+import (
+    "errors"
+    "net/http"
+    "shei-deli/apperrors"
+    "shei-deli/i18n"
+    "shei-deli/middleware"
+    "shei-deli/models"
+    "shei-deli/moderation"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+)
+
+// TranslationController serves the translations of recipes into other languages
+type TranslationController struct {
+    recipes repository.RecipeRepository
+    users   repository.UserRepository
+    filter  *moderation.Filter
+}
+
+// NewTranslationController builds a TranslationController from s
+func NewTranslationController(s Services) *TranslationController {
+    return &TranslationController{recipes: s.Recipes, users: s.Users, filter: s.Filter}
+}
+
+// RecipeTranslationRequest represents a recipe's text in another language;
+// fields left empty show the original text
+type RecipeTranslationRequest struct {
+    Title        string `json:"title" binding:"required,max=200"`
+    Description  string `json:"description" binding:"max=1000"`
+    Ingredients  string `json:"ingredients" binding:"max=10000"`
+    Instructions string `json:"instructions" binding:"max=20000"`
+}
+
+// localizeRecipes shows recipes in the request's language where their authors translated them
+func localizeRecipes(c *gin.Context, repo repository.RecipeRepository, recipes []models.Recipe) error {
+    if err := repo.Localize(recipes, middleware.GetLocale(c)); err != nil {
+        return apperrors.Internal("Error retrieving recipe translations", err)
+    }
+    return nil
+}
+
+// localizeRecipe shows a recipe in the request's language if its author translated it
+func localizeRecipe(c *gin.Context, repo repository.RecipeRepository, recipe *models.Recipe) error {
+    if err := repo.LocalizeOne(recipe, middleware.GetLocale(c)); err != nil {
+        return apperrors.Internal("Error retrieving recipe translations", err)
+    }
+    return nil
+}
+
+// GetRecipeTranslations lists the languages a recipe has been translated into
+func (tc *TranslationController) GetRecipeTranslations(c *gin.Context) {
+    recipe, err := tc.findRecipe(c, true)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    translations, err := tc.recipes.Translations(recipe.ID)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving recipe translations", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "locale":       recipe.Locale,
+        "translations": translations,
+    })
+}
+
+// PutRecipeTranslation creates or replaces a recipe's translation into :locale.
+// Translations are screened by the content filter like edits to the recipe.
+func (tc *TranslationController) PutRecipeTranslation(c *gin.Context) {
+    recipe, err := tc.findRecipe(c, false)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    if err := tc.checkAuthor(c, recipe); err != nil {
+        c.Error(err)
+        return
+    }
+    locale, err := translationLocale(c, recipe)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    var req RecipeTranslationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
+        return
+    }
+
+    // Held translations hold the whole recipe, since it is shown in either language
+    status, note := tc.filter.Status(recipe.Status, recipe.ModerationNote, req.Title, req.Description, req.Ingredients, req.Instructions)
+
+    translation := models.RecipeTranslation{
+        Locale:       locale,
+        Title:        req.Title,
+        Description:  req.Description,
+        Ingredients:  req.Ingredients,
+        Instructions: req.Instructions,
+    }
+    created, err := tc.recipes.SaveTranslation(&recipe, &translation, status, note)
+    if err != nil {
+        c.Error(apperrors.Internal("Error saving recipe translation", err))
+        return
+    }
+
+    code := http.StatusOK
+    if created {
+        code = http.StatusCreated
+    }
+    c.JSON(code, gin.H{
+        "translation": translation,
+        "status":      status,
+    })
+}
+
+// DeleteRecipeTranslation removes a recipe's translation into :locale
+func (tc *TranslationController) DeleteRecipeTranslation(c *gin.Context) {
+    recipe, err := tc.findRecipe(c, false)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    if err := tc.checkAuthor(c, recipe); err != nil {
+        c.Error(err)
+        return
+    }
+    locale, err := translationLocale(c, recipe)
+    if err != nil {
+        c.Error(err)
+        return
+    }
+
+    if err := tc.recipes.DeleteTranslation(recipe.ID, locale); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            c.Error(apperrors.NotFound("Recipe translation"))
+            return
+        }
+        c.Error(apperrors.Internal("Error deleting recipe translation", err))
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "Recipe translation deleted successfully",
+    })
+}
+
+// findRecipe loads the recipe named by :id; reading translations requires an
+// approved one, while its author can manage the translations of any
+func (tc *TranslationController) findRecipe(c *gin.Context, approved bool) (models.Recipe, error) {
+    id, err := paramID(c, "id", "Recipe")
+    if err != nil {
+        return models.Recipe{}, err
+    }
+    find := tc.recipes.Find
+    if approved {
+        find = tc.recipes.FindApproved
+    }
+    recipe, err := find(id)
+    if err != nil {
+        return recipe, apperrors.FromDB(err, "Recipe")
+    }
+    return recipe, nil
+}
+
+// checkAuthor checks that the user authenticated by RequireUser is the
+// recipe's active author, the only one who may manage its translations
+func (tc *TranslationController) checkAuthor(c *gin.Context, recipe models.Recipe) error {
+    user, _ := middleware.GetAccountUser(c)
+    if user.ID != recipe.UserID {
+        return apperrors.Forbidden("You can only translate your own recipes")
+    }
+    _, err := activeUser(tc.users, user.ID)
+    return err
+}
+
+// translationLocale reads the :locale parameter, which must be a supported
+// language other than the one the recipe was written in
+func translationLocale(c *gin.Context, recipe models.Recipe) (string, error) {
+    locale, ok := i18n.Match(c.Param("locale"))
+    if !ok {
+        return "", apperrors.Validation([]FieldError{{Field: "locale", Message: localeMessage()}})
+    }
+    if locale == recipe.Locale {
+        return "", apperrors.Validation([]FieldError{{Field: "locale", Message: "is the language the recipe was written in; update the recipe instead"}})
+    }
+    return locale, nil
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "time"
+    "shei-deli/apperrors"
+    "shei-deli/jobs"
+    "shei-deli/middleware"
+    "shei-deli/models"
+    "shei-deli/repository"
+    "shei-deli/storage"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+// TrashController serves users' deleted recipes and reviews
+type TrashController struct {
+    recipes  repository.RecipeRepository
+    feedback repository.FeedbackRepository
+    users    repository.UserRepository
+}
+
+// NewTrashController builds a TrashController from s
+func NewTrashController(s Services) *TrashController {
+    return &TrashController{recipes: s.Recipes, feedback: s.Feedback, users: s.Users}
+}
+
+// TrashedRecipe is a deleted recipe in a user's trash
+type TrashedRecipe struct {
+    models.Recipe
+    RestorableUntil time.Time `json:"restorable_until"`
+}
+
+// TrashedFeedback is a deleted review in a user's trash
+type TrashedFeedback struct {
+    models.Feedback
+    RestorableUntil time.Time `json:"restorable_until"`
+}
+
+// GetUserTrash lists a user's deleted recipes and reviews that can still be
+// restored, most recently deleted first
+func (tc *TrashController) GetUserTrash(c *gin.Context) {
+    id, err := paramID(c, "id", "User")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    user, err := tc.users.Find(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "User"))
+        return
+    }
+
+    cutoff := time.Now().Add(-models.TrashRetention)
+    recipes, err := tc.recipes.Trash(user.ID, cutoff)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving deleted recipes", err))
+        return
+    }
+    feedbacks, err := tc.feedback.Trash(user.ID, cutoff)
+    if err != nil {
+        c.Error(apperrors.Internal("Error retrieving deleted feedback", err))
+        return
+    }
+
+    trashedRecipes := make([]TrashedRecipe, len(recipes))
+    for i, r := range recipes {
+        trashedRecipes[i] = TrashedRecipe{Recipe: r, RestorableUntil: models.RestorableUntil(r.DeletedAt)}
+    }
+    trashedFeedbacks := make([]TrashedFeedback, len(feedbacks))
+    for i, f := range feedbacks {
+        trashedFeedbacks[i] = TrashedFeedback{Feedback: f, RestorableUntil: models.RestorableUntil(f.DeletedAt)}
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "recipes":        trashedRecipes,
+        "feedbacks":      trashedFeedbacks,
+        "retention_days": int(models.TrashRetention.Hours() / 24),
+    })
+}
+
+// RestoreRecipe brings a deleted recipe and its reviews back from the trash
+func (tc *TrashController) RestoreRecipe(c *gin.Context) {
+    id, err := paramID(c, "id", "Deleted recipe")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    recipe, err := tc.recipes.FindTrashed(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Deleted recipe"))
+        return
+    }
+    if err := restorable(recipe.DeletedAt, "Deleted recipe"); err != nil {
+        c.Error(err)
+        return
+    }
+    if err := canRestore(c, recipe.UserID); err != nil {
+        c.Error(err)
+        return
+    }
+
+    if err := tc.recipes.Restore(&recipe); err != nil {
+        c.Error(apperrors.Internal("Error restoring recipe", err))
+        return
+    }
+    c.JSON(http.StatusOK, recipe)
+}
+
+// RestoreFeedback brings a deleted review back from the trash
+func (tc *TrashController) RestoreFeedback(c *gin.Context) {
+    id, err := paramID(c, "id", "Deleted feedback")
+    if err != nil {
+        c.Error(err)
+        return
+    }
+    feedback, err := tc.feedback.FindTrashed(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "Deleted feedback"))
+        return
+    }
+    if err := restorable(feedback.DeletedAt, "Deleted feedback"); err != nil {
+        c.Error(err)
+        return
+    }
+    if err := canRestore(c, feedback.UserID); err != nil {
+        c.Error(err)
+        return
+    }
+
+    if _, err := tc.recipes.Find(feedback.RecipeID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            c.Error(apperrors.Conflict("The recipe was deleted; restore the recipe to bring its reviews back"))
+            return
+        }
+        c.Error(apperrors.Internal("Error retrieving recipe", err))
+        return
+    }
+
+    if err := tc.feedback.Restore(&feedback); err != nil {
+        c.Error(apperrors.Internal("Error restoring feedback", err))
+        return
+    }
+    c.JSON(http.StatusOK, feedback)
+}
+
+// canRestore checks that the user authenticated by RequireUser is the active
+// author of a trashed item, or an admin
+func canRestore(c *gin.Context, authorID uint) *apperrors.Error {
+    user, _ := middleware.GetAccountUser(c)
+    if !user.IsActive {
+        return apperrors.Forbidden("This account is deactivated")
+    }
+    if user.ID != authorID && user.Role != models.RoleAdmin {
+        return apperrors.Forbidden("You can only restore your own content")
+    }
+    return nil
+}
+
+// restorable rejects trashed rows that are past the retention window
+func restorable(deletedAt gorm.DeletedAt, resource string) *apperrors.Error {
+    if time.Now().After(models.RestorableUntil(deletedAt)) {
+        return apperrors.Gone(resource + " is past the restore window and will be purged")
+    }
+    return nil
+}
+
+// PurgeTrash permanently removes everything deleted before the retention
+// window, including uploaded images in store
+func PurgeTrash(db *gorm.DB, store storage.BlobStore, now time.Time) (models.PurgeResult, error) {
+    result, err := models.PurgeExpired(db, now.Add(-models.TrashRetention))
+    if err != nil {
+        return result, err
+    }
+    newUploads(store).remove(result.MediaURLs)
+    return result, nil
+}
+
+// StartTrashPurger purges expired trash immediately and then every interval in
+// the background. Call the returned function to stop it.
+func StartTrashPurger(db *gorm.DB, store storage.BlobStore, interval time.Duration) func() {
+    return jobs.Every(interval, func() {
+        result, err := PurgeTrash(db, store, time.Now())
+        if err != nil {
+            log.Printf("Failed to purge trash: %v", err)
+            return
+        }
+        if result.Recipes > 0 || result.Feedbacks > 0 {
+            log.Printf("Purged trash: %d recipes, %d reviews", result.Recipes, result.Feedbacks)
+        }
+    })
+}
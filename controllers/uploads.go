@@ -0,0 +1,75 @@
+package controllers
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "mime/multipart"
+    "shei-deli/apperrors"
+    "shei-deli/images"
+    "shei-deli/storage"
+)
+
+const maxReviewPhotos = 5
+
+// uploads stores uploaded images, with their variants, in a media store
+type uploads struct {
+    store  storage.BlobStore
+    images *images.Service
+}
+
+func newUploads(store storage.BlobStore) uploads {
+    return uploads{store: store, images: images.NewService(store)}
+}
+
+// save validates and stores an uploaded image with its card and
+// thumbnail variants. field names the form field in validation errors.
+func (u uploads) save(header *multipart.FileHeader, field, prefix string) (images.Stored, error) {
+    if header.Size > u.images.MaxBytes {
+        return images.Stored{}, u.validationError(field, images.ErrTooLarge)
+    }
+
+    file, err := header.Open()
+    if err != nil {
+        return images.Stored{}, apperrors.Internal("Failed to read upload", err)
+    }
+    defer file.Close()
+
+    stored, err := u.images.Save(file, prefix)
+    if err != nil {
+        if verr := u.validationError(field, err); verr != nil {
+            return images.Stored{}, verr
+        }
+        return images.Stored{}, apperrors.Internal("Failed to save image", err)
+    }
+    return stored, nil
+}
+
+// validationError converts an image rejection into a field-level
+// validation error, or returns nil for other errors
+func (u uploads) validationError(field string, err error) error {
+    var message string
+    switch {
+    case errors.Is(err, images.ErrTooLarge):
+        message = fmt.Sprintf("must be at most %d MB", u.images.MaxBytes>>20)
+    case errors.Is(err, images.ErrUnsupportedType):
+        message = "must be a JPEG, PNG, GIF or WebP image"
+    case errors.Is(err, images.ErrTooManyPixels):
+        message = fmt.Sprintf("must be at most %dx%d pixels", u.images.MaxWidth, u.images.MaxHeight)
+    case errors.Is(err, images.ErrInvalidImage):
+        message = "is not a readable image"
+    default:
+        return nil
+    }
+    return apperrors.Validation([]FieldError{{Field: field, Message: message}})
+}
+
+// remove deletes previously stored images and their variants; URLs
+// outside the upload directory are ignored
+func (u uploads) remove(urls []string) {
+    for _, url := range urls {
+        if err := u.images.Delete(url); err != nil {
+            log.Printf("Failed to delete upload %s: %v", url, err)
+        }
+    }
+}
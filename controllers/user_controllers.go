@@ -1,51 +1,109 @@
 package controllers
 
 import (
+    "errors"
     "net/http"
+    "shei-deli/apperrors"
     "shei-deli/models"
-    "shei-deli/config"
+    "shei-deli/i18n"
+    "shei-deli/middleware"
+    "shei-deli/repository"
     "github.com/gin-gonic/gin"
     "golang.org/x/crypto/bcrypt"
 )
 
+// UserController serves registration, sign-in and user profiles
+type UserController struct {
+    users   repository.UserRepository
+    recipes repository.RecipeRepository
+}
+
+// NewUserController builds a UserController from s
+func NewUserController(s Services) *UserController {
+    return &UserController{users: s.Users, recipes: s.Recipes}
+}
+
+// activeUser loads the user acting on a request. Deactivated and deleted
+// accounts are refused, so they cannot post, vote, report or edit.
+func activeUser(users repository.UserRepository, id uint) (models.User, error) {
+    user, err := users.Find(id)
+    if err != nil {
+        return user, apperrors.FromDB(err, "User")
+    }
+    if !user.IsActive || user.IsAnonymized() {
+        return user, apperrors.Forbidden("This account is deactivated")
+    }
+    return user, nil
+}
+
+// AccountIdentity is the username and email every account is created with;
+// registration and the `user create` command apply the same rules to them
+type AccountIdentity struct {
+    Username string `json:"username" binding:"required,min=3,max=30,alphanum"`
+    Email    string `json:"email" binding:"required,email,max=254"`
+}
+
+// ValidateAccountIdentity checks a username and email for a new account
+func ValidateAccountIdentity(username, email string) []FieldError {
+    return validateRequest(AccountIdentity{Username: username, Email: email})
+}
+
 // UserRegistration represents the registration request
 type UserRegistration struct {
-    Username  string `json:"username" binding:"required"`
-    Email     string `json:"email" binding:"required"`
-    Password  string `json:"password" binding:"required"`
-    FirstName string `json:"first_name"`
-    LastName  string `json:"last_name"`
-    Bio       string `json:"bio"`
+    AccountIdentity
+    Password  string `json:"password" binding:"required,min=8,max=72,password"`
+    FirstName string `json:"first_name" binding:"max=50"`
+    LastName  string `json:"last_name" binding:"max=50"`
+    Bio       string `json:"bio" binding:"max=500"`
+    Locale    string `json:"locale" binding:"omitempty,locale"` // Defaults to the language the request was served in
+}
+
+// UserLogin represents the login request
+type UserLogin struct {
+    Username string `json:"username" binding:"required"`
+    Password string `json:"password" binding:"required"`
+}
+
+// UserProfileUpdate represents the profile update request; empty fields are left unchanged
+type UserProfileUpdate struct {
+    FirstName string `json:"first_name" binding:"max=50"`
+    LastName  string `json:"last_name" binding:"max=50"`
+    Bio       string `json:"bio" binding:"max=500"`
+    AvatarURL string `json:"avatar_url" binding:"omitempty,url,max=500"`
+    Locale    string `json:"locale" binding:"omitempty,locale"`
 }
 
 // RegisterUser creates a new user account
-func RegisterUser(c *gin.Context) {
+func (uc *UserController) RegisterUser(c *gin.Context) {
     var regData UserRegistration
     if err := c.ShouldBindJSON(&regData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
-        return
-    }
-
-    // Validate required fields
-    if regData.Username == "" || regData.Email == "" || regData.Password == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Username, email, and password are required"})
+        respondValidationError(c, fieldErrors(err))
         return
     }
     
     // Check if username or email already exists
-    var existingUser models.User
-    if err := config.DB.Where("username = ? OR email = ?", regData.Username, regData.Email).First(&existingUser).Error; err == nil {
-        c.JSON(http.StatusConflict, gin.H{"error": "Username or email already exists"})
+    taken, err := uc.users.Taken(regData.Username, regData.Email)
+    if err != nil {
+        c.Error(apperrors.Internal("Error checking existing users", err))
+        return
+    }
+    if taken {
+        c.Error(apperrors.Conflict("Username or email already exists"))
         return
     }
 
     // Hash password
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(regData.Password), bcrypt.DefaultCost)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing password"})
+        c.Error(apperrors.Internal("Error processing password", err))
         return
     }
 
+    locale := middleware.GetLocale(c)
+    if regData.Locale != "" {
+        locale, _ = i18n.Match(regData.Locale)
+    }
+
     // Create user model
     user := models.User{
         Username:  regData.Username,
@@ -54,11 +112,12 @@ func RegisterUser(c *gin.Context) {
         FirstName: regData.FirstName,
         LastName:  regData.LastName,
         Bio:       regData.Bio,
+        Locale:    locale,
         IsActive:  true,
     }
 
-    if err := config.DB.Create(&user).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
+    if err := uc.users.Create(&user); err != nil {
+        c.Error(apperrors.Internal("Error creating user", err))
         return
     }
     
@@ -72,36 +131,41 @@ func RegisterUser(c *gin.Context) {
 }
 
 // LoginUser authenticates a user (basic implementation)
-func LoginUser(c *gin.Context) {
-    var loginData struct {
-        Username string `json:"username"`
-        Password string `json:"password"`
-    }
-    
+func (uc *UserController) LoginUser(c *gin.Context) {
+    var loginData UserLogin
     if err := c.ShouldBindJSON(&loginData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
+        respondValidationError(c, fieldErrors(err))
         return
     }
     
     // Find user by username or email
-    var user models.User
-    if err := config.DB.Where("username = ? OR email = ?", loginData.Username, loginData.Username).First(&user).Error; err != nil {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+    user, err := uc.users.FindByLogin(loginData.Username)
+    if err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            c.Error(apperrors.Unauthorized("Invalid credentials"))
+        } else {
+            c.Error(apperrors.Internal("Error retrieving user", err))
+        }
         return
     }
     
     // Check password
     if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password)); err != nil {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+        c.Error(apperrors.Unauthorized("Invalid credentials"))
         return
     }
     
     // Check if user is active
     if !user.IsActive {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+        c.Error(apperrors.Unauthorized("Account is deactivated"))
         return
     }
     
+    // Pages in this browser follow the user's language from now on
+    if locale, ok := i18n.Match(user.Locale); ok {
+        middleware.SetLocaleCookie(c, locale)
+    }
+
     // Remove password from response
     user.Password = ""
     
@@ -113,54 +177,63 @@ func LoginUser(c *gin.Context) {
 }
 
 // GetUserProfile fetches user profile information
-func GetUserProfile(c *gin.Context) {
-    id := c.Param("id")
+func (uc *UserController) GetUserProfile(c *gin.Context) {
+    id, err := paramID(c, "id", "User")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
-    var user models.User
-    if err := config.DB.Preload("Recipes").Preload("Feedbacks").First(&user, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+    user, err := uc.users.FindProfile(id)
+    if err != nil {
+        c.Error(apperrors.FromDB(err, "User"))
         return
     }
     
     // Remove password from response
     user.Password = ""
     
-    // Calculate user statistics
-    var recipeCount int64
-    var feedbackCount int64
-    config.DB.Model(&models.Recipe{}).Where("user_id = ?", user.ID).Count(&recipeCount)
-    config.DB.Model(&models.Feedback{}).Where("user_id = ?", user.ID).Count(&feedbackCount)
-    
+    // The profile carries the user's approved recipes and reviews
     c.JSON(http.StatusOK, gin.H{
         "user":           user,
-        "recipe_count":   recipeCount,
-        "feedback_count": feedbackCount,
+        "recipe_count":   len(user.Recipes),
+        "feedback_count": len(user.Feedbacks),
     })
 }
 
 // UpdateUserProfile updates user profile information
-func UpdateUserProfile(c *gin.Context) {
-    id := c.Param("id")
+func (uc *UserController) UpdateUserProfile(c *gin.Context) {
+    id, err := paramID(c, "id", "User")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
-    var user models.User
-    if err := config.DB.First(&user, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+    user, err := activeUser(uc.users, id)
+    if err != nil {
+        c.Error(err)
         return
     }
     
-    var updateData models.User
-    if err := c.ShouldBindJSON(&updateData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data format"})
+    var req UserProfileUpdate
+    if err := c.ShouldBindJSON(&req); err != nil {
+        respondValidationError(c, fieldErrors(err))
         return
     }
     
-    // Don't allow updating sensitive fields through this endpoint
-    updateData.Password = ""
-    updateData.Username = ""
-    updateData.Email = ""
+    // Only profile fields can be updated through this endpoint
+    updateData := models.User{
+        FirstName: req.FirstName,
+        LastName:  req.LastName,
+        Bio:       req.Bio,
+        AvatarURL: req.AvatarURL,
+    }
+    if req.Locale != "" {
+        updateData.Locale, _ = i18n.Match(req.Locale)
+    }
     
-    if err := config.DB.Model(&user).Updates(updateData).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating profile"})
+    if err := uc.users.UpdateProfile(&user, updateData); err != nil {
+        c.Error(apperrors.Internal("Error updating profile", err))
         return
     }
     
@@ -174,36 +247,53 @@ func UpdateUserProfile(c *gin.Context) {
 }
 
 // GetAllUsers fetches all users (admin function)
-func GetAllUsers(c *gin.Context) {
-    var users []models.User
-    if err := config.DB.Select("id, username, email, first_name, last_name, bio, avatar_url, is_active, joined_at").Find(&users).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving users"})
+func (uc *UserController) GetAllUsers(c *gin.Context) {
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
         return
     }
     
+    users, pageInfo, err := uc.users.List(pageReq)
+    if err != nil {
+        c.Error(listError(err, "Error retrieving users"))
+        return
+    }
+    linkPage(c, &pageInfo)
+    
     c.JSON(http.StatusOK, gin.H{
-        "users": users,
+        "users":      users,
+        "pagination": pageInfo,
     })
 }
 
 // GetUserRecipes fetches all recipes by a specific user
-func GetUserRecipes(c *gin.Context) {
-    userID := c.Param("id")
+func (uc *UserController) GetUserRecipes(c *gin.Context) {
+    userID, err := paramID(c, "id", "User")
+    if err != nil {
+        c.Error(err)
+        return
+    }
     
-    var recipes []models.Recipe
-    if err := config.DB.Preload("User").Preload("Feedbacks").Where("user_id = ?", userID).Find(&recipes).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user recipes"})
+    pageReq, err := parsePageRequest(c)
+    if err != nil {
+        c.Error(err)
         return
     }
     
-    // Calculate average ratings
-    for i := range recipes {
-        var avgRating float64
-        config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipes[i].ID).Select("AVG(rating)").Scan(&avgRating)
-        recipes[i].AverageRating = avgRating
+    recipes, pageInfo, err := uc.recipes.List(repository.RecipeFilter{UserID: userID}, pageReq, repository.SortOption{})
+    if err != nil {
+        c.Error(listError(err, "Error retrieving user recipes"))
+        return
+    }
+    linkPage(c, &pageInfo)
+    if err := localizeRecipes(c, uc.recipes, recipes); err != nil {
+        c.Error(err)
+        return
     }
     
     c.JSON(http.StatusOK, gin.H{
-        "recipes": recipes,
+        "recipes":    recipes,
+        "pagination": pageInfo,
     })
 }
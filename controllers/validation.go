@@ -0,0 +1,178 @@
+package controllers
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+    "shei-deli/apperrors"
+    "shei-deli/i18n"
+    "shei-deli/models"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+    "github.com/gin-gonic/gin/binding"
+    "github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+    Field   string `json:"field"`
+    Message string `json:"message"`
+}
+
+func init() {
+    if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+        // Report fields by their JSON/form names rather than Go struct names
+        v.RegisterTagNameFunc(func(field reflect.StructField) string {
+            for _, tag := range []string{"json", "form"} {
+                name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+                if name != "" && name != "-" {
+                    return name
+                }
+            }
+            return field.Name
+        })
+        v.RegisterValidation("password", validatePasswordStrength)
+        v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+            return slugPattern.MatchString(fl.Field().String())
+        })
+        v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+            _, ok := i18n.Match(fl.Field().String())
+            return ok
+        })
+    }
+}
+
+// slugPattern matches keys such as category keys: lowercase words joined by underscores
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// validatePasswordStrength applies models.StrongPassword
+func validatePasswordStrength(fl validator.FieldLevel) bool {
+    return models.StrongPassword(fl.Field().String())
+}
+
+// validateRequest runs the binding validator over an already populated request
+func validateRequest(req interface{}) []FieldError {
+    if err := binding.Validator.ValidateStruct(req); err != nil {
+        return fieldErrors(err)
+    }
+    return nil
+}
+
+// validateParsed runs the binding validator over a request read from a form,
+// skipping fields that already failed to parse so each field reports one error
+func validateParsed(req interface{}, fields []FieldError) []FieldError {
+    failed := make(map[string]bool, len(fields))
+    for _, f := range fields {
+        failed[f.Field] = true
+    }
+    for _, f := range validateRequest(req) {
+        if !failed[f.Field] {
+            fields = append(fields, f)
+        }
+    }
+    return fields
+}
+
+// checkTaxonomy adds errors for a category or cuisine that is given but not
+// known. Validator tags can't check these since the lists live in the database.
+func checkTaxonomy(taxonomy repository.TaxonomyRepository, fields []FieldError, category, cuisine string) []FieldError {
+    if category != "" && !taxonomy.IsCategory(category) {
+        fields = append(fields, FieldError{Field: "category", Message: "must be a valid category"})
+    }
+    if cuisine != "" && !taxonomy.IsCuisine(cuisine) {
+        fields = append(fields, FieldError{Field: "cuisine", Message: "must be a valid cuisine"})
+    }
+    return fields
+}
+
+// paramID reads a numeric ID path parameter; anything else cannot name a row,
+// so it is reported as resource not found
+func paramID(c *gin.Context, name, resource string) (uint, error) {
+    id, err := parseID(c.Param(name), resource)
+    if err != nil {
+        return 0, err
+    }
+    return id, nil
+}
+
+// parseID reads a numeric ID, reporting anything else as resource not found
+func parseID(value, resource string) (uint, *apperrors.Error) {
+    id, err := strconv.ParseUint(value, 10, 0)
+    if err != nil {
+        return 0, apperrors.NotFound(resource)
+    }
+    return uint(id), nil
+}
+
+// fieldErrors converts binding and validation errors into field-level messages
+func fieldErrors(err error) []FieldError {
+    var validationErrs validator.ValidationErrors
+    if errors.As(err, &validationErrs) {
+        fields := make([]FieldError, 0, len(validationErrs))
+        for _, fe := range validationErrs {
+            fields = append(fields, FieldError{
+                Field:   fe.Field(),
+                Message: validationMessage(fe),
+            })
+        }
+        return fields
+    }
+
+    var typeErr *json.UnmarshalTypeError
+    if errors.As(err, &typeErr) {
+        return []FieldError{{
+            Field:   typeErr.Field,
+            Message: fmt.Sprintf("must be a %s", typeErr.Type.Kind()),
+        }}
+    }
+
+    return []FieldError{{Field: "body", Message: "must be valid JSON"}}
+}
+
+// localeMessage describes the languages a locale field accepts
+func localeMessage() string {
+    return "must be a supported language: " + strings.Join(i18n.Default.Locales(), ", ")
+}
+
+// validationMessage returns a human-readable message for a failed validation tag
+func validationMessage(fe validator.FieldError) string {
+    switch fe.Tag() {
+    case "required":
+        return "is required"
+    case "email":
+        return "must be a valid email address"
+    case "url":
+        return "must be a valid URL"
+    case "alphanum":
+        return "must contain only letters and numbers"
+    case "oneof":
+        return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+    case "password":
+        return "must contain at least one letter and one number"
+    case "slug":
+        return "must contain only lowercase letters, numbers and single underscores"
+    case "locale":
+        return localeMessage()
+    case "min":
+        if fe.Kind() == reflect.String {
+            return fmt.Sprintf("must be at least %s characters long", fe.Param())
+        }
+        return fmt.Sprintf("must be at least %s", fe.Param())
+    case "max":
+        if fe.Kind() == reflect.String {
+            return fmt.Sprintf("must be at most %s characters long", fe.Param())
+        }
+        return fmt.Sprintf("must be at most %s", fe.Param())
+    default:
+        return "is invalid"
+    }
+}
+
+// respondValidationError records a validation error listing every failing field
+func respondValidationError(c *gin.Context, fields []FieldError) {
+    c.Error(apperrors.Validation(fields))
+}
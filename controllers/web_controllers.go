@@ -1,22 +1,45 @@
 package controllers
 
 import (
-    "database/sql"
+    "errors"
     "net/http"
-    "sort"
     "strconv"
-    "time"
     "shei-deli/models"
-    "shei-deli/config"
+    "shei-deli/i18n"
+    "shei-deli/middleware"
+    "shei-deli/ranking"
+    "shei-deli/repository"
     "github.com/gin-gonic/gin"
 )
 
-// Category represents a recipe category for the UI
-type Category struct {
-    Key         string
-    Name        string
-    Description string
-    Image       string
+// WebController serves the HTML pages
+type WebController struct {
+    recipes  repository.RecipeRepository
+    feedback repository.FeedbackRepository
+    users    repository.UserRepository
+    taxonomy repository.TaxonomyRepository
+}
+
+// NewWebController builds a WebController from s
+func NewWebController(s Services) *WebController {
+    return &WebController{recipes: s.Recipes, feedback: s.Feedback, users: s.Users, taxonomy: s.Taxonomy}
+}
+
+// renderHTML renders a page with the request's locale and the languages the
+// page can be switched to
+func renderHTML(c *gin.Context, code int, name string, data gin.H) {
+    data["Locale"] = middleware.GetLocale(c)
+    data["Languages"] = i18n.Languages()
+    c.HTML(code, name, data)
+}
+
+// renderError renders the error page with a translated title and message
+func renderError(c *gin.Context, code int, titleKey, messageKey string) {
+    locale := middleware.GetLocale(c)
+    renderHTML(c, code, "error.html", gin.H{
+        "Title": i18n.T(locale, titleKey),
+        "Error": i18n.T(locale, messageKey),
+    })
 }
 
 // Stats represents application statistics
@@ -26,267 +49,207 @@ type Stats struct {
     TotalFeedback int64
 }
 
-// GetCategories returns all available categories
-func getCategories() []Category {
-    return []Category{
-        {
-            Key:         "plant_based_meals",
-            Name:        "Plant-Based Meals",
-            Description: "Vegan/vegetarian options (no animal products)",
-            Image:       "vegan.jpeg",
-        },
-        {
-            Key:         "kids_meals",
-            Name:        "Kids' Meals",
-            Description: "Fun, simple, and nutritious meals for children",
-            Image:       "kids-meals.jpeg",
-        },
-        {
-            Key:         "light_meals",
-            Name:        "Light Meals (Weight Loss)",
-            Description: "Low-calorie, balanced recipes",
-            Image:       "light-meals.jpeg",
-        },
-        {
-            Key:         "hearty_meals",
-            Name:        "Hearty Meals (Weight Gain)",
-            Description: "High-calorie, energy-packed recipes",
-            Image:       "hearty-meals.jpeg",
-        },
-        {
-            Key:         "meat_stews",
-            Name:        "Meat Stews",
-            Description: "Beef, chicken, goat, lamb, and other meat-based stews",
-            Image:       "stews.jpeg",
-        },
-        {
-            Key:         "veggie_stews",
-            Name:        "Veggie Stews",
-            Description: "Lentil, bean, mushroom, and vegetable stews",
-            Image:       "vegetable-stews.jpeg",
-        },
-        {
-            Key:         "seafood_stews",
-            Name:        "Seafood & Fish Stews",
-            Description: "Fish stews, seafood mixes, and ocean-inspired flavors",
-            Image:       "fish&sea-food.jpeg",
-        },
-        {
-            Key:         "fusion_stews",
-            Name:        "Fusion Stews",
-            Description: "Cultural and traditional varieties (e.g., goulash, curries)",
-            Image:       "fusion.jpeg",
-        },
-        {
-            Key:         "soups",
-            Name:        "Soups",
-            Description: "Warm, comforting soups",
-            Image:       "soups.jpeg",
-        },
-        {
-            Key:         "drinks",
-            Name:        "Drinks",
-            Description: "Smoothies, juices, teas, and other beverages",
-            Image:       "drinks&smoothies.jpeg",
-        },
-        {
-            Key:         "pastries",
-            Name:        "Pastries",
-            Description: "Baked goods such as cakes, cookies, pies, and breads",
-            Image:       "pastries.jpeg",
-        },
-    }
-}
-
 // HomeHandler serves the home page
-func HomeHandler(c *gin.Context) {
-    // Get stats
+func (wc *WebController) HomeHandler(c *gin.Context) {
+    // Get stats; the page still renders without them
     var stats Stats
-    config.DB.Model(&models.Recipe{}).Count(&stats.TotalRecipes)
-    config.DB.Model(&models.User{}).Count(&stats.TotalUsers)
-    config.DB.Model(&models.Feedback{}).Count(&stats.TotalFeedback)
+    stats.TotalRecipes, _ = wc.recipes.Count()
+    stats.TotalUsers, _ = wc.users.Count()
+    stats.TotalFeedback, _ = wc.feedback.Count()
+
+    categories, err := wc.taxonomy.Categories()
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_categories")
+        return
+    }
 
-    c.HTML(http.StatusOK, "index.html", gin.H{
-        "Title":      "Home",
-        "Categories": getCategories(),
+    // Subcategories are reached from their parent's page
+    locale := middleware.GetLocale(c)
+    renderHTML(c, http.StatusOK, "index.html", gin.H{
+        "Title":      i18n.T(locale, "page.home"),
+        "Categories": models.LocalizeCategories(models.CategoryTree(categories), locale),
         "Stats":      stats,
     })
 }
 
 // CategoryHandler serves the category page
-func CategoryHandler(c *gin.Context) {
+func (wc *WebController) CategoryHandler(c *gin.Context) {
     categoryKey := c.Param("category")
     
-    // Find category info
-    var categoryInfo Category
-    categories := getCategories()
-    for _, cat := range categories {
-        if cat.Key == categoryKey {
-            categoryInfo = cat
-            break
+    categoryInfo, err := wc.taxonomy.FindCategory(categoryKey)
+    if err != nil {
+        if !errors.Is(err, repository.ErrNotFound) {
+            renderError(c, http.StatusInternalServerError, "page.error", "error.load_category")
+            return
         }
-    }
-    
-    if categoryInfo.Key == "" {
-        c.HTML(http.StatusNotFound, "error.html", gin.H{
-            "Title": "Category Not Found",
-            "Error": "The requested category was not found.",
-        })
+        renderError(c, http.StatusNotFound, "page.category_not_found", "error.category_not_found")
         return
     }
 
-    // Get recipes for this category
-    var recipes []models.Recipe
-    if err := config.DB.Preload("User").Preload("Feedbacks").Where("category = ?", categoryKey).Find(&recipes).Error; err != nil {
-        c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-            "Title": "Error",
-            "Error": "Failed to load recipes for this category.",
-        })
+    // Unknown sort keys fall back to the default rather than erroring on the page
+    sortKey := c.DefaultQuery("sort", defaultRecipeSort)
+    sortOpt, ok := recipeSortOptions[sortKey]
+    if !ok {
+        sortKey = defaultRecipeSort
+        sortOpt = recipeSortOptions[sortKey]
+    }
+
+    // Get recipes for this category and its subcategories
+    keys, err := wc.taxonomy.CategoryKeys(categoryInfo.Key)
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_category")
         return
     }
+    subcategories, _ := wc.taxonomy.Subcategories(categoryInfo.Key)
+    locale := middleware.GetLocale(c)
+    var parent *models.Category
+    if p, err := wc.taxonomy.FindCategory(categoryInfo.ParentKey); err == nil {
+        p = p.Localized(locale)
+        parent = &p
+    }
+    categoryInfo = categoryInfo.Localized(locale)
 
-    // Calculate average ratings
-    for i := range recipes {
-        var avgRating sql.NullFloat64
-        config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipes[i].ID).Select("AVG(rating)").Scan(&avgRating)
-        if avgRating.Valid {
-            recipes[i].AverageRating = avgRating.Float64
-        } else {
-            recipes[i].AverageRating = 0.0
-        }
+    recipes, err := wc.recipes.All(repository.RecipeFilter{Categories: keys}, sortOpt)
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_category_recipes")
+        return
+    }
+    if err := wc.recipes.Localize(recipes, locale); err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_category_recipes")
+        return
     }
 
-    c.HTML(http.StatusOK, "category.html", gin.H{
+    renderHTML(c, http.StatusOK, "category.html", gin.H{
         "Title":               categoryInfo.Name,
         "CategoryName":        categoryInfo.Name,
         "CategoryDescription": categoryInfo.Description,
         "CategoryKey":         categoryKey,
+        "Subcategories":       models.LocalizeCategories(subcategories, locale),
+        "Parent":              parent,
         "Recipes":             recipes,
+        "SortOptions":         recipeSortChoices(),
+        "SelectedSort":        sortKey,
     })
 }
 
 // RecipeHandler serves the recipe detail page
-func RecipeHandler(c *gin.Context) {
-    id := c.Param("id")
+func (wc *WebController) RecipeHandler(c *gin.Context) {
+    id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+    if err != nil {
+        renderError(c, http.StatusNotFound, "page.recipe_not_found", "error.recipe_not_found")
+        return
+    }
     
-    var recipe models.Recipe
-    if err := config.DB.Preload("User").Preload("Feedbacks.User").First(&recipe, id).Error; err != nil {
-        c.HTML(http.StatusNotFound, "error.html", gin.H{
-            "Title": "Recipe Not Found",
-            "Error": "The requested recipe was not found.",
-        })
+    recipe, err := wc.recipes.FindApproved(uint(id))
+    if err != nil {
+        if !errors.Is(err, repository.ErrNotFound) {
+            renderError(c, http.StatusInternalServerError, "page.error", "error.load_recipe")
+            return
+        }
+        renderError(c, http.StatusNotFound, "page.recipe_not_found", "error.recipe_not_found")
         return
     }
 
-    // Calculate average rating
-    var avgRating sql.NullFloat64
-    config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", recipe.ID).Select("AVG(rating)").Scan(&avgRating)
-    if avgRating.Valid {
-        recipe.AverageRating = avgRating.Float64
-    } else {
-        recipe.AverageRating = 0.0
+    // Most helpful reviews first, matching the feedback API's default sort
+    if recipe.Feedbacks, err = wc.feedback.Reviews(recipe.ID); err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_recipe")
+        return
+    }
+    if err := wc.recipes.LocalizeOne(&recipe, middleware.GetLocale(c)); err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_recipe")
+        return
     }
 
-    c.HTML(http.StatusOK, "recipe.html", gin.H{
+    // Latest community photos for the gallery; the API pages through the rest
+    photos, _ := wc.feedback.LatestPhotos(recipe.ID, 24)
+
+    renderHTML(c, http.StatusOK, "recipe.html", gin.H{
         "Title":  recipe.Title,
         "Recipe": recipe,
+        "Photos": photos,
     })
 }
 
 // AddRecipeHandler serves the add recipe form
-func AddRecipeHandler(c *gin.Context) {
+func (wc *WebController) AddRecipeHandler(c *gin.Context) {
     selectedCategory := c.Query("category")
     
-    c.HTML(http.StatusOK, "add-recipe.html", gin.H{
-        "Title":            "Add Recipe",
-        "Categories":       getCategories(),
+    categories, err := wc.taxonomy.Categories()
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_categories")
+        return
+    }
+
+    cuisines, err := wc.taxonomy.Cuisines()
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_cuisines")
+        return
+    }
+
+    locale := middleware.GetLocale(c)
+    renderHTML(c, http.StatusOK, "add-recipe.html", gin.H{
+        "Title":            i18n.T(locale, "page.add_recipe"),
+        "Categories":       models.LocalizeCategories(categories, locale),
+        "Cuisines":         cuisines,
         "SelectedCategory": selectedCategory,
     })
 }
 
 // RegisterHandler serves the user registration form
 func RegisterHandler(c *gin.Context) {
-    c.HTML(http.StatusOK, "register.html", gin.H{
-        "Title": "Join Community",
+    renderHTML(c, http.StatusOK, "register.html", gin.H{
+        "Title": i18n.T(middleware.GetLocale(c), "page.join"),
     })
 }
 
-// FeaturedHandler serves featured recipes page (highly-rated and popular recipes)
-func FeaturedHandler(c *gin.Context) {
-    page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-    limit := 12
-    offset := (page - 1) * limit
-
-    // Get all recipes with their feedbacks to calculate ratings
-    var allRecipes []models.Recipe
-    if err := config.DB.Preload("User").Preload("Feedbacks").Find(&allRecipes).Error; err != nil {
-        c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-            "Title": "Error",
-            "Error": "Failed to load recipes.",
-        })
-        return
+// FeaturedHandler serves featured recipes page (recipes trending in ?window=)
+func (wc *WebController) FeaturedHandler(c *gin.Context) {
+    window, err := parseTrendingWindow(c)
+    if err != nil {
+        window = defaultTrendingWindow
     }
-
-    // Calculate average ratings and filter featured recipes
-    var featuredRecipes []models.Recipe
-    for i := range allRecipes {
-        var avgRating sql.NullFloat64
-        config.DB.Model(&models.Feedback{}).Where("recipe_id = ?", allRecipes[i].ID).Select("AVG(rating)").Scan(&avgRating)
-        if avgRating.Valid {
-            allRecipes[i].AverageRating = avgRating.Float64
-        } else {
-            allRecipes[i].AverageRating = 0.0
-        }
-
-        // Featured criteria: rating >= 4.0 OR has 2+ feedbacks OR is recent (created in last 30 days)
-        feedbackCount := len(allRecipes[i].Feedbacks)
-        isRecent := allRecipes[i].CreatedAt.After(time.Now().AddDate(0, 0, -30))
-
-        if allRecipes[i].AverageRating >= 4.0 || feedbackCount >= 2 || isRecent {
-            featuredRecipes = append(featuredRecipes, allRecipes[i])
-        }
+    page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+    if err != nil || page < 1 {
+        page = 1
     }
 
-    // Sort featured recipes by rating (descending), then by feedback count, then by creation date
-    sort.Slice(featuredRecipes, func(i, j int) bool {
-        if featuredRecipes[i].AverageRating != featuredRecipes[j].AverageRating {
-            return featuredRecipes[i].AverageRating > featuredRecipes[j].AverageRating
-        }
-        if len(featuredRecipes[i].Feedbacks) != len(featuredRecipes[j].Feedbacks) {
-            return len(featuredRecipes[i].Feedbacks) > len(featuredRecipes[j].Feedbacks)
-        }
-        return featuredRecipes[i].CreatedAt.After(featuredRecipes[j].CreatedAt)
-    })
-
-    // Apply pagination to featured recipes
-    totalFeatured := len(featuredRecipes)
-    totalPages := (totalFeatured + limit - 1) / limit
-
-    start := offset
-    end := offset + limit
-    if start > totalFeatured {
-        start = totalFeatured
+    params, err := trendingParams(wc.recipes)
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_recipes")
+        return
+    }
+    recipes, pageInfo, err := featuredRecipes(wc.recipes, window, PageRequest{Limit: 12, Page: page})
+    if err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_recipes")
+        return
     }
-    if end > totalFeatured {
-        end = totalFeatured
+    if err := wc.recipes.Localize(recipes, middleware.GetLocale(c)); err != nil {
+        renderError(c, http.StatusInternalServerError, "page.error", "error.load_recipes")
+        return
     }
 
-    paginatedRecipes := featuredRecipes[start:end]
-
-    c.HTML(http.StatusOK, "base.html", gin.H{
-        "Title":       "Featured Recipes",
+    renderHTML(c, http.StatusOK, "base.html", gin.H{
+        "Title":       i18n.T(middleware.GetLocale(c), "page.featured"),
         "Template":    "featured.html",
-        "Recipes":     paginatedRecipes,
+        "Recipes":     recipes,
         "CurrentPage": page,
-        "TotalPages":  totalPages,
-        "HasNext":     page < totalPages,
+        "TotalPages":  pageInfo.TotalPages,
+        "HasNext":     page < pageInfo.TotalPages,
         "HasPrev":     page > 1,
+        "Window":      window,
+        "Windows":     ranking.Windows,
+        "Ranking":     params,
     })
 }
 
 // AboutHandler serves the about page
-func AboutHandler(c *gin.Context) {
-    c.HTML(http.StatusOK, "about.html", gin.H{
-        "Title": "About Shei-deli",
+func (wc *WebController) AboutHandler(c *gin.Context) {
+    // The list is informational, so the page still renders without it
+    categories, _ := wc.taxonomy.Categories()
+
+    locale := middleware.GetLocale(c)
+    renderHTML(c, http.StatusOK, "about.html", gin.H{
+        "Title":      i18n.T(locale, "page.about"),
+        "Categories": models.LocalizeCategories(categories, locale),
     })
 }
@@ -0,0 +1,504 @@
+package fixtures
+
+import (
+    "bytes"
+    "crypto/rand"
+    "embed"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+    "shei-deli/models"
+    "golang.org/x/crypto/bcrypt"
+    "gopkg.in/yaml.v3"
+    "gorm.io/gorm"
+)
+
+//go:embed *.yaml
+var setFiles embed.FS
+
+// Set is the seed data of one fixture file. Every row has a stable key
+// (category key, username, recipe slug, or recipe and username for feedback)
+// so applying a set again finds the rows it created before.
+type Set struct {
+    Categories []Category `json:"categories"`
+    Users      []User     `json:"users"`
+    Recipes    []Recipe   `json:"recipes"`
+    Feedback   []Feedback `json:"feedback"`
+}
+
+// Category is a category to create in addition to the default ones
+type Category struct {
+    Key         string `json:"key"`
+    Parent      string `json:"parent,omitempty"` // Key of the parent category
+    Name        string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Image       string `json:"image,omitempty"`
+    SortOrder   int    `json:"sort_order,omitempty"`
+}
+
+// User is an account, keyed by username
+type User struct {
+    Username  string `json:"username"`
+    Email     string `json:"email"`
+    Password  string `json:"password,omitempty"` // A random one when empty
+    Role      string `json:"role,omitempty"`     // Defaults to user
+    FirstName string `json:"first_name,omitempty"`
+    LastName  string `json:"last_name,omitempty"`
+    Bio       string `json:"bio,omitempty"`
+    Locale    string `json:"locale,omitempty"`
+}
+
+// Recipe is an approved recipe keyed by slug. The author defaults to
+// Options.Author.
+type Recipe struct {
+    Slug string `json:"slug"`
+    models.RecipeRecord
+}
+
+// Feedback is a review, keyed by the recipe's slug and the reviewer's username
+type Feedback struct {
+    Recipe  string `json:"recipe"`
+    User    string `json:"user"`
+    Rating  int    `json:"rating"`
+    Comment string `json:"comment,omitempty"`
+}
+
+// Options controls how Apply treats rows that already exist
+type Options struct {
+    Author string // Username credited for recipes that name no author
+    Update bool   // Bring existing rows in line with the set; otherwise they are left alone
+}
+
+// Result counts the rows Apply changed
+type Result struct {
+    Created int
+    Updated int // Includes recipes seeded before slugs that were given theirs
+}
+
+// Names lists the built-in fixture sets
+func Names() []string {
+    entries, _ := fs.ReadDir(setFiles, ".")
+    names := make([]string, 0, len(entries))
+    for _, entry := range entries {
+        names = append(names, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Load returns the built-in set called name, or reads the file at name
+// when there is no such set
+func Load(name string) (*Set, error) {
+    if data, err := setFiles.ReadFile(name + ".yaml"); err == nil {
+        return Parse(data, ".yaml")
+    }
+    return LoadFile(name)
+}
+
+// LoadFile reads a set from a .yaml, .yml or .json file
+func LoadFile(file string) (*Set, error) {
+    data, err := os.ReadFile(file)
+    if err != nil {
+        return nil, err
+    }
+    set, err := Parse(data, filepath.Ext(file))
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", file, err)
+    }
+    return set, nil
+}
+
+// Parse decodes a set written in the format named by ext. YAML is read as
+// JSON would be, so both formats use the same field names and unknown fields
+// are errors in either.
+func Parse(data []byte, ext string) (*Set, error) {
+    switch strings.ToLower(ext) {
+    case ".yaml", ".yml":
+        var doc interface{}
+        if err := yaml.Unmarshal(data, &doc); err != nil {
+            return nil, err
+        }
+        converted, err := json.Marshal(doc)
+        if err != nil {
+            return nil, err
+        }
+        data = converted
+    case ".json":
+    default:
+        return nil, fmt.Errorf("unsupported fixture format %q (want .yaml, .yml or .json)", ext)
+    }
+
+    var set Set
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(&set); err != nil {
+        return nil, err
+    }
+    return &set, nil
+}
+
+// Apply creates the rows of set that are missing, and with opts.Update
+// rewrites existing ones to match, in a single transaction. Rows that were
+// deleted are left deleted. Recipes seeded by title before they had slugs are
+// adopted rather than duplicated.
+func Apply(db *gorm.DB, set *Set, opts Options) (Result, error) {
+    var result Result
+    err := db.Transaction(func(tx *gorm.DB) error {
+        a := applier{tx: tx, opts: opts, result: &result, categories: make(map[string]bool), users: make(map[string]uint), recipes: make(map[string]uint), reviews: make(map[[2]string]bool)}
+        for _, category := range set.Categories {
+            if err := a.category(category); err != nil {
+                return fmt.Errorf("category %q: %w", category.Key, err)
+            }
+        }
+        for _, user := range set.Users {
+            if err := a.user(user); err != nil {
+                return fmt.Errorf("user %q: %w", user.Username, err)
+            }
+        }
+        for _, recipe := range set.Recipes {
+            if err := a.recipe(recipe); err != nil {
+                return fmt.Errorf("recipe %q: %w", recipe.Slug, err)
+            }
+        }
+        for _, review := range set.Feedback {
+            if err := a.review(review); err != nil {
+                return fmt.Errorf("feedback by %q on %q: %w", review.User, review.Recipe, err)
+            }
+        }
+        if a.reviewsChanged {
+            if _, err := models.RecomputeRatingAggregates(tx); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return Result{}, err
+    }
+    return result, nil
+}
+
+// find loads the first row matching query into dest and reports whether there
+// was one. Unlike First it doesn't log a missing row, which is expected here.
+func find(query *gorm.DB, dest interface{}) (bool, error) {
+    result := query.Limit(1).Find(dest)
+    return result.RowsAffected > 0, result.Error
+}
+
+// applier holds the state of one Apply
+type applier struct {
+    tx             *gorm.DB
+    opts           Options
+    result         *Result
+    categories     map[string]bool
+    users          map[string]uint // IDs by username
+    recipes        map[string]uint // IDs by slug
+    reviews        map[[2]string]bool
+    reviewsChanged bool
+}
+
+// category upserts one category by key
+func (a *applier) category(fixture Category) error {
+    if fixture.Key == "" || fixture.Name == "" {
+        return errors.New("key and name are required")
+    }
+    if a.categories[fixture.Key] {
+        return errors.New("appears twice")
+    }
+    a.categories[fixture.Key] = true
+
+    want := models.Category{Key: fixture.Key, ParentKey: fixture.Parent, Name: fixture.Name, Description: fixture.Description, Image: fixture.Image, SortOrder: fixture.SortOrder}
+    var existing models.Category
+    found, err := find(a.tx.Unscoped().Where(&models.Category{Key: fixture.Key}), &existing)
+    if err != nil {
+        return err
+    }
+    if !found {
+        if err := a.tx.Create(&want).Error; err != nil {
+            return err
+        }
+        a.result.Created++
+        return nil
+    }
+    if !a.opts.Update || existing.DeletedAt.Valid {
+        return nil
+    }
+    if existing.ParentKey == want.ParentKey && existing.Name == want.Name && existing.Description == want.Description && existing.Image == want.Image && existing.SortOrder == want.SortOrder {
+        return nil
+    }
+    updates := map[string]interface{}{"parent_key": want.ParentKey, "name": want.Name, "description": want.Description, "image": want.Image, "sort_order": want.SortOrder}
+    if err := a.tx.Model(&existing).Updates(updates).Error; err != nil {
+        return err
+    }
+    a.result.Updated++
+    return nil
+}
+
+// user upserts one account by username
+func (a *applier) user(fixture User) error {
+    if fixture.Username == "" || !strings.Contains(fixture.Email, "@") {
+        return errors.New("username and email are required")
+    }
+    if _, ok := a.users[fixture.Username]; ok {
+        return errors.New("appears twice")
+    }
+    role := fixture.Role
+    if role == "" {
+        role = models.RoleUser
+    }
+    if role != models.RoleUser && role != models.RoleModerator && role != models.RoleAdmin {
+        return fmt.Errorf("unknown role %q", role)
+    }
+
+    var existing models.User
+    found, err := find(a.tx.Unscoped().Where("username = ?", fixture.Username), &existing)
+    if err != nil {
+        return err
+    }
+    if !found {
+        password, err := hashPassword(fixture.Password)
+        if err != nil {
+            return err
+        }
+        user := models.User{Username: fixture.Username, Email: fixture.Email, Password: password, Role: role, FirstName: fixture.FirstName, LastName: fixture.LastName, Bio: fixture.Bio, Locale: fixture.Locale, IsActive: true}
+        if err := a.tx.Create(&user).Error; err != nil {
+            return err
+        }
+        a.users[fixture.Username] = user.ID
+        a.result.Created++
+        return nil
+    }
+    a.users[fixture.Username] = existing.ID
+    if !a.opts.Update || existing.DeletedAt.Valid {
+        return nil
+    }
+
+    updates := map[string]interface{}{}
+    for column, values := range map[string][2]string{
+        "email":      {existing.Email, fixture.Email},
+        "role":       {existing.Role, role},
+        "first_name": {existing.FirstName, fixture.FirstName},
+        "last_name":  {existing.LastName, fixture.LastName},
+        "bio":        {existing.Bio, fixture.Bio},
+        "locale":     {existing.Locale, fixture.Locale},
+    } {
+        if values[0] != values[1] {
+            updates[column] = values[1]
+        }
+    }
+    // Passwords are only replaced when the fixture names one that no longer matches
+    if fixture.Password != "" && bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(fixture.Password)) != nil {
+        password, err := hashPassword(fixture.Password)
+        if err != nil {
+            return err
+        }
+        updates["password"] = password
+    }
+    if len(updates) == 0 {
+        return nil
+    }
+    if err := a.tx.Model(&existing).Updates(updates).Error; err != nil {
+        return err
+    }
+    a.result.Updated++
+    return nil
+}
+
+// hashPassword hashes password, or a random one nobody knows when it is empty
+func hashPassword(password string) (string, error) {
+    if password == "" {
+        random := make([]byte, 24)
+        if _, err := rand.Read(random); err != nil {
+            return "", err
+        }
+        password = hex.EncodeToString(random)
+    }
+    hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return "", err
+    }
+    return string(hashed), nil
+}
+
+// recipe upserts one approved recipe by slug
+func (a *applier) recipe(fixture Recipe) error {
+    if fixture.Slug == "" {
+        return errors.New("slug is required")
+    }
+    if _, ok := a.recipes[fixture.Slug]; ok {
+        return errors.New("appears twice")
+    }
+    username := fixture.Author
+    if username == "" {
+        username = a.opts.Author
+    }
+    authorID, err := a.userID(username)
+    if err != nil {
+        return err
+    }
+    want, err := models.NewRecipe(a.tx, fixture.RecipeRecord, authorID)
+    if err != nil {
+        return err
+    }
+    slug := fixture.Slug
+    want.Slug = &slug
+
+    var existing models.Recipe
+    found, err := find(a.tx.Unscoped().Preload("Translations").Where("slug = ?", slug), &existing)
+    if err != nil {
+        return err
+    }
+    if !found {
+        // Sample recipes seeded before slugs existed are matched by title and author
+        if found, err = find(a.tx.Preload("Translations").Where("slug IS NULL AND title = ? AND user_id = ?", want.Title, authorID), &existing); err != nil {
+            return err
+        }
+        if found {
+            if err := a.tx.Model(&existing).Update("slug", slug).Error; err != nil {
+                return err
+            }
+            if !a.opts.Update {
+                a.recipes[slug] = existing.ID
+                a.result.Updated++
+                return nil
+            }
+        }
+    }
+    if !found {
+        // Creating the recipe creates its translations too
+        if err := a.tx.Create(&want).Error; err != nil {
+            return err
+        }
+        a.recipes[slug] = want.ID
+        a.result.Created++
+        return nil
+    }
+    a.recipes[slug] = existing.ID
+    if !a.opts.Update || existing.DeletedAt.Valid {
+        return nil
+    }
+
+    updates := recipeColumns(want)
+    sameTranslations := reflect.DeepEqual(translationTexts(existing.Translations), translationTexts(want.Translations))
+    if reflect.DeepEqual(updates, recipeColumns(existing)) && sameTranslations {
+        return nil
+    }
+    if err := a.tx.Model(&existing).Updates(updates).Error; err != nil {
+        return err
+    }
+    if !sameTranslations {
+        if err := a.tx.Where("recipe_id = ?", existing.ID).Delete(&models.RecipeTranslation{}).Error; err != nil {
+            return err
+        }
+        for _, translation := range want.Translations {
+            translation.RecipeID = existing.ID
+            if err := a.tx.Create(&translation).Error; err != nil {
+                return err
+            }
+        }
+    }
+    a.result.Updated++
+    return nil
+}
+
+// recipeColumns are the columns of recipe a fixture controls
+func recipeColumns(recipe models.Recipe) map[string]interface{} {
+    return map[string]interface{}{
+        "title":        recipe.Title,
+        "description":  recipe.Description,
+        "ingredients":  recipe.Ingredients,
+        "instructions": recipe.Instructions,
+        "category":     recipe.Category,
+        "cuisine":      recipe.Cuisine,
+        "locale":       recipe.Locale,
+        "prep_time":    recipe.PrepTime,
+        "cook_time":    recipe.CookTime,
+        "servings":     recipe.Servings,
+        "difficulty":   recipe.Difficulty,
+        "image_url":    recipe.ImageURL,
+        "user_id":      recipe.UserID,
+        "status":       recipe.Status,
+    }
+}
+
+// translationTexts is the comparable content of translations, by locale
+func translationTexts(translations []models.RecipeTranslation) map[string][4]string {
+    texts := make(map[string][4]string, len(translations))
+    for _, translation := range translations {
+        texts[translation.Locale] = [4]string{translation.Title, translation.Description, translation.Ingredients, translation.Instructions}
+    }
+    return texts
+}
+
+// userID finds an account by username, in the set or the database
+func (a *applier) userID(username string) (uint, error) {
+    if id, ok := a.users[username]; ok {
+        return id, nil
+    }
+    var user models.User
+    if found, err := find(a.tx.Where("username = ?", username), &user); err != nil {
+        return 0, err
+    } else if !found || username == "" {
+        return 0, fmt.Errorf("unknown user %q", username)
+    }
+    a.users[username] = user.ID
+    return user.ID, nil
+}
+
+// review upserts one review by recipe and reviewer
+func (a *applier) review(fixture Feedback) error {
+    key := [2]string{fixture.Recipe, fixture.User}
+    if a.reviews[key] {
+        return errors.New("appears twice")
+    }
+    a.reviews[key] = true
+    if fixture.Rating < 1 || fixture.Rating > 5 {
+        return errors.New("rating must be between 1 and 5")
+    }
+    recipeID, ok := a.recipes[fixture.Recipe]
+    if !ok {
+        var recipe models.Recipe
+        if found, err := find(a.tx.Where("slug = ?", fixture.Recipe), &recipe); err != nil {
+            return err
+        } else if !found || fixture.Recipe == "" {
+            return fmt.Errorf("unknown recipe %q", fixture.Recipe)
+        }
+        recipeID = recipe.ID
+    }
+    userID, err := a.userID(fixture.User)
+    if err != nil {
+        return err
+    }
+
+    var existing models.Feedback
+    found, err := find(a.tx.Unscoped().Where("recipe_id = ? AND user_id = ?", recipeID, userID), &existing)
+    if err != nil {
+        return err
+    }
+    if !found {
+        review := models.Feedback{RecipeID: recipeID, UserID: userID, Rating: fixture.Rating, Comment: fixture.Comment, Status: models.StatusApproved}
+        if err := a.tx.Create(&review).Error; err != nil {
+            return err
+        }
+        a.result.Created++
+        a.reviewsChanged = true
+        return nil
+    }
+    if !a.opts.Update || existing.DeletedAt.Valid || (existing.Rating == fixture.Rating && existing.Comment == fixture.Comment) {
+        return nil
+    }
+    if err := a.tx.Model(&existing).Updates(map[string]interface{}{"rating": fixture.Rating, "comment": fixture.Comment}).Error; err != nil {
+        return err
+    }
+    a.result.Updated++
+    a.reviewsChanged = true
+    return nil
+}
@@ -0,0 +1,218 @@
+package i18n
+
+import (
+    "embed"
+    "encoding/json"
+    "fmt"
+    "io/fs"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// DefaultLocale is used when nothing better matches and for missing messages
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Catalog maps message keys to translated messages; messages may contain
+// fmt verbs filled in by T
+type Catalog map[string]string
+
+// Language describes a supported locale for language pickers
+type Language struct {
+    Code string `json:"code"`
+    Name string `json:"name"` // The language's name in that language
+}
+
+// Bundle holds the message catalogs of all supported locales
+type Bundle struct {
+    defaultLocale string
+    catalogs      map[string]Catalog
+}
+
+// NewBundle creates an empty bundle that falls back to defaultLocale
+func NewBundle(defaultLocale string) *Bundle {
+    return &Bundle{
+        defaultLocale: normalize(defaultLocale),
+        catalogs:      make(map[string]Catalog),
+    }
+}
+
+// Default is the bundle loaded from the catalogs in locales/
+var Default = mustLoadDefault()
+
+func mustLoadDefault() *Bundle {
+    bundle := NewBundle(DefaultLocale)
+    if err := bundle.LoadFS(localeFiles, "locales/*.json"); err != nil {
+        panic(err)
+    }
+    return bundle
+}
+
+// AddCatalog adds messages for a locale, replacing existing ones with the same key
+func (b *Bundle) AddCatalog(locale string, messages Catalog) {
+    locale = normalize(locale)
+    catalog, ok := b.catalogs[locale]
+    if !ok {
+        catalog = make(Catalog, len(messages))
+        b.catalogs[locale] = catalog
+    }
+    for key, message := range messages {
+        catalog[key] = message
+    }
+}
+
+// LoadFS loads every JSON catalog matching pattern; the file name without its
+// extension is the locale, e.g. locales/fr.json
+func (b *Bundle) LoadFS(fsys fs.FS, pattern string) error {
+    files, err := fs.Glob(fsys, pattern)
+    if err != nil {
+        return err
+    }
+    for _, file := range files {
+        data, err := fs.ReadFile(fsys, file)
+        if err != nil {
+            return err
+        }
+        var messages Catalog
+        if err := json.Unmarshal(data, &messages); err != nil {
+            return fmt.Errorf("i18n: parsing %s: %w", file, err)
+        }
+        b.AddCatalog(strings.TrimSuffix(path.Base(file), path.Ext(file)), messages)
+    }
+    return nil
+}
+
+// Locales returns the supported locales, default first
+func (b *Bundle) Locales() []string {
+    locales := make([]string, 0, len(b.catalogs))
+    for locale := range b.catalogs {
+        if locale != b.defaultLocale {
+            locales = append(locales, locale)
+        }
+    }
+    sort.Strings(locales)
+    return append([]string{b.defaultLocale}, locales...)
+}
+
+// Languages returns the supported locales with their names
+func (b *Bundle) Languages() []Language {
+    var languages []Language
+    for _, locale := range b.Locales() {
+        languages = append(languages, Language{Code: locale, Name: b.T(locale, "language.name")})
+    }
+    return languages
+}
+
+// Match returns the supported locale for a language tag such as "fr-CA",
+// falling back from a regional variant to its base language
+func (b *Bundle) Match(tag string) (string, bool) {
+    tag = normalize(tag)
+    if tag == "" {
+        return "", false
+    }
+    if _, ok := b.catalogs[tag]; ok {
+        return tag, true
+    }
+    if base, _, found := strings.Cut(tag, "-"); found {
+        if _, ok := b.catalogs[base]; ok {
+            return base, true
+        }
+    }
+    return "", false
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header,
+// honouring q-values, or the default locale when none match
+func (b *Bundle) Negotiate(acceptLanguage string) string {
+    best, bestQ := b.defaultLocale, 0.0
+    for _, part := range strings.Split(acceptLanguage, ",") {
+        tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        q := 1.0
+        if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            parsed, err := strconv.ParseFloat(value, 64)
+            if err != nil {
+                continue
+            }
+            q = parsed
+        }
+        // Ties go to the earlier entry, as listed by the client
+        if locale, ok := b.Match(tag); ok && q > bestQ {
+            best, bestQ = locale, q
+        }
+    }
+    return best
+}
+
+// Lookup finds the message for key in locale, falling back to the base
+// language and then the default locale
+func (b *Bundle) Lookup(locale, key string) (string, bool) {
+    for _, candidate := range b.Fallbacks(locale) {
+        if message, ok := b.catalogs[candidate][key]; ok {
+            return message, true
+        }
+    }
+    return "", false
+}
+
+// T translates key into locale, formatting args into the message. Unknown
+// keys return the key itself so missing translations stay visible.
+func (b *Bundle) T(locale, key string, args ...interface{}) string {
+    message, ok := b.Lookup(locale, key)
+    if !ok {
+        return key
+    }
+    if len(args) > 0 {
+        return fmt.Sprintf(message, args...)
+    }
+    return message
+}
+
+// Fallbacks returns the locales tried for locale, in order: the locale itself,
+// its base language and the default locale
+func (b *Bundle) Fallbacks(locale string) []string {
+    locale = normalize(locale)
+    candidates := []string{locale}
+    if base, _, found := strings.Cut(locale, "-"); found {
+        candidates = append(candidates, base)
+    }
+    return append(candidates, b.defaultLocale)
+}
+
+// normalize lower-cases a language tag and uses "-" as the separator
+func normalize(tag string) string {
+    return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// T translates key with the default bundle
+func T(locale, key string, args ...interface{}) string {
+    return Default.T(locale, key, args...)
+}
+
+// Lookup finds a message in the default bundle
+func Lookup(locale, key string) (string, bool) {
+    return Default.Lookup(locale, key)
+}
+
+// Fallbacks returns the default bundle's fallback chain for locale
+func Fallbacks(locale string) []string {
+    return Default.Fallbacks(locale)
+}
+
+// Match returns the default bundle's locale for a language tag
+func Match(tag string) (string, bool) {
+    return Default.Match(tag)
+}
+
+// Negotiate picks a default bundle locale for an Accept-Language header
+func Negotiate(acceptLanguage string) string {
+    return Default.Negotiate(acceptLanguage)
+}
+
+// Languages returns the default bundle's supported languages
+func Languages() []Language {
+    return Default.Languages()
+}
@@ -0,0 +1,205 @@
+package images
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "image"
+    "image/jpeg"
+    "io"
+    "net/http"
+    "strings"
+    "shei-deli/storage"
+    _ "image/gif"
+    _ "image/png"
+    "golang.org/x/image/draw"
+    _ "golang.org/x/image/webp"
+)
+
+// Errors returned for uploads that are not acceptable images
+var (
+    ErrTooLarge        = errors.New("image file is too large")
+    ErrUnsupportedType = errors.New("image must be a JPEG, PNG, GIF or WebP")
+    ErrTooManyPixels   = errors.New("image dimensions are too large")
+    ErrInvalidImage    = errors.New("image could not be decoded")
+)
+
+// allowedTypes are the sniffed content types accepted for upload
+var allowedTypes = map[string]bool{
+    "image/jpeg": true,
+    "image/png":  true,
+    "image/gif":  true,
+    "image/webp": true,
+}
+
+// Variant is a stored rendition of an upload. Cropped variants fill the box
+// exactly; others are scaled down to fit within it.
+type Variant struct {
+    Suffix string
+    Width  int
+    Height int
+    Crop   bool
+}
+
+// Variants stored for every upload; the first is the main image
+var (
+    Full      = Variant{Suffix: "", Width: 1600, Height: 1600}
+    Card      = Variant{Suffix: "_card", Width: 600, Height: 400, Crop: true}
+    Thumbnail = Variant{Suffix: "_thumb", Width: 200, Height: 200, Crop: true}
+    Variants  = []Variant{Full, Card, Thumbnail}
+)
+
+// Stored holds the public URLs of a saved upload's variants
+type Stored struct {
+    URL          string `json:"url"`
+    CardURL      string `json:"card_url"`
+    ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// Service validates uploaded images, normalises them and stores their variants
+type Service struct {
+    Store     storage.BlobStore
+    MaxBytes  int64
+    MaxWidth  int
+    MaxHeight int
+    MaxPixels int    // Guards against decompression bombs with modest-looking dimensions
+    Quality   int
+}
+
+// NewService returns a service storing images in store
+func NewService(store storage.BlobStore) *Service {
+    return &Service{
+        Store:     store,
+        MaxBytes:  10 << 20,
+        MaxWidth:  8000,
+        MaxHeight: 8000,
+        MaxPixels: 40_000_000,
+        Quality:   85,
+    }
+}
+
+// Save validates an image, applies its EXIF orientation, re-encodes it as JPEG
+// (which drops EXIF and any other metadata) and stores every variant under a
+// random name starting with prefix
+func (s *Service) Save(r io.Reader, prefix string) (Stored, error) {
+    data, err := io.ReadAll(io.LimitReader(r, s.MaxBytes+1))
+    if err != nil {
+        return Stored{}, err
+    }
+    if int64(len(data)) > s.MaxBytes {
+        return Stored{}, ErrTooLarge
+    }
+    if !allowedTypes[http.DetectContentType(data)] {
+        return Stored{}, ErrUnsupportedType
+    }
+
+    // Check dimensions from the header before decoding any pixels
+    cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+    if err != nil {
+        return Stored{}, ErrInvalidImage
+    }
+    if cfg.Width > s.MaxWidth || cfg.Height > s.MaxHeight || cfg.Width*cfg.Height > s.MaxPixels {
+        return Stored{}, ErrTooManyPixels
+    }
+
+    img, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+        return Stored{}, ErrInvalidImage
+    }
+    img = applyOrientation(img, exifOrientation(data))
+
+    base, err := randomName(prefix)
+    if err != nil {
+        return Stored{}, err
+    }
+
+    ctx := context.Background()
+    var written []string
+    for _, v := range Variants {
+        var buf bytes.Buffer
+        if err := jpeg.Encode(&buf, resize(img, v), &jpeg.Options{Quality: s.Quality}); err != nil {
+            return Stored{}, err
+        }
+        key := base + v.Suffix + ".jpg"
+        if err := s.Store.Put(ctx, key, &buf, "image/jpeg"); err != nil {
+            for _, w := range written {
+                s.Store.Delete(ctx, w)
+            }
+            return Stored{}, err
+        }
+        written = append(written, key)
+    }
+
+    return Stored{
+        URL:          storage.MediaURL(base + Full.Suffix + ".jpg"),
+        CardURL:      storage.MediaURL(base + Card.Suffix + ".jpg"),
+        ThumbnailURL: storage.MediaURL(base + Thumbnail.Suffix + ".jpg"),
+    }, nil
+}
+
+// Delete removes a stored image and its variants. URLs that are not stored
+// media (such as the default category images) are ignored.
+func (s *Service) Delete(url string) error {
+    key, ok := storage.KeyFromURL(url)
+    if !ok {
+        return nil
+    }
+    base := strings.TrimSuffix(key, ".jpg")
+    for _, v := range Variants {
+        base = strings.TrimSuffix(base, v.Suffix)
+    }
+
+    // Keys migrated from older uploads may keep another extension and have no variants
+    keys := []string{key}
+    for _, v := range Variants {
+        if k := base + v.Suffix + ".jpg"; k != key {
+            keys = append(keys, k)
+        }
+    }
+    for _, k := range keys {
+        if err := s.Store.Delete(context.Background(), k); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// resize scales img down to fit the variant, cropping to its aspect ratio first
+// for cropped variants. Images are never scaled up.
+func resize(img image.Image, v Variant) image.Image {
+    src := img.Bounds()
+    if v.Crop {
+        // Centre crop to the variant's aspect ratio
+        w, h := src.Dx(), src.Dy()
+        if w*v.Height > h*v.Width {
+            w = h * v.Width / v.Height
+        } else {
+            h = w * v.Height / v.Width
+        }
+        x0 := src.Min.X + (src.Dx()-w)/2
+        y0 := src.Min.Y + (src.Dy()-h)/2
+        src = image.Rect(x0, y0, x0+w, y0+h)
+    }
+
+    w, h := src.Dx(), src.Dy()
+    scale := min(float64(v.Width)/float64(w), float64(v.Height)/float64(h), 1)
+    dw, dh := max(int(float64(w)*scale), 1), max(int(float64(h)*scale), 1)
+
+    // Draw onto white so transparent PNG/GIF areas do not turn black in JPEG
+    dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+    draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+    draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+    return dst
+}
+
+// randomName returns an unguessable file name (without extension) starting with prefix
+func randomName(prefix string) (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", fmt.Errorf("generating file name: %w", err)
+    }
+    return prefix + "_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,111 @@
+package images
+
+import (
+    "encoding/binary"
+    "image"
+)
+
+// exifOrientation returns the EXIF orientation (1-8) of JPEG data, or 1 when
+// it is absent or unreadable
+func exifOrientation(data []byte) int {
+    if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+        return 1
+    }
+
+    // Walk the JPEG segments looking for the APP1 Exif block
+    for i := 2; i+4 <= len(data); {
+        if data[i] != 0xFF {
+            return 1
+        }
+        marker := data[i+1]
+        size := int(binary.BigEndian.Uint16(data[i+2:]))
+        if marker == 0xDA || size < 2 || i+2+size > len(data) {
+            return 1 // Start of scan: no more metadata
+        }
+        segment := data[i+4 : i+2+size]
+        if marker == 0xE1 && len(segment) >= 6 && string(segment[:6]) == "Exif\x00\x00" {
+            return tiffOrientation(segment[6:])
+        }
+        i += 2 + size
+    }
+    return 1
+}
+
+// tiffOrientation reads the Orientation tag from the first IFD of a TIFF header
+func tiffOrientation(tiff []byte) int {
+    if len(tiff) < 8 {
+        return 1
+    }
+    var order binary.ByteOrder
+    switch string(tiff[:2]) {
+    case "II":
+        order = binary.LittleEndian
+    case "MM":
+        order = binary.BigEndian
+    default:
+        return 1
+    }
+
+    ifd := int(order.Uint32(tiff[4:]))
+    if ifd+2 > len(tiff) {
+        return 1
+    }
+    entries := int(order.Uint16(tiff[ifd:]))
+    for e := 0; e < entries; e++ {
+        entry := ifd + 2 + e*12
+        if entry+12 > len(tiff) {
+            return 1
+        }
+        if order.Uint16(tiff[entry:]) == 0x0112 {
+            orientation := int(order.Uint16(tiff[entry+8:]))
+            if orientation < 1 || orientation > 8 {
+                return 1
+            }
+            return orientation
+        }
+    }
+    return 1
+}
+
+// applyOrientation rotates and flips img so it displays upright once the EXIF
+// orientation is stripped
+func applyOrientation(img image.Image, orientation int) image.Image {
+    if orientation == 1 {
+        return img
+    }
+
+    b := img.Bounds()
+    w, h := b.Dx(), b.Dy()
+    // Orientations 5-8 swap width and height
+    dw, dh := w, h
+    if orientation >= 5 {
+        dw, dh = h, w
+    }
+    dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+    for y := 0; y < h; y++ {
+        for x := 0; x < w; x++ {
+            var dx, dy int
+            switch orientation {
+            case 2: // Mirrored horizontally
+                dx, dy = w-1-x, y
+            case 3: // Rotated 180°
+                dx, dy = w-1-x, h-1-y
+            case 4: // Mirrored vertically
+                dx, dy = x, h-1-y
+            case 5: // Mirrored along the top-left diagonal
+                dx, dy = y, x
+            case 6: // Rotated 90° clockwise
+                dx, dy = h-1-y, x
+            case 7: // Mirrored along the top-right diagonal
+                dx, dy = h-1-y, w-1-x
+            case 8: // Rotated 90° counter-clockwise
+                dx, dy = y, w-1-x
+            default:
+                dx, dy = x, y
+            }
+            dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+        }
+    }
+    return dst
+}
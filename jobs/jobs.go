@@ -0,0 +1,26 @@
+package jobs
+
+import (
+    "time"
+)
+
+// Every runs job immediately and then every interval in the background. Call
+// the returned function to stop it; a run already in progress finishes first.
+func Every(interval time.Duration, job func()) func() {
+    done := make(chan struct{})
+
+    job()
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ticker.C:
+                job()
+            case <-done:
+                return
+            }
+        }
+    }()
+    return func() { close(done) }
+}
@@ -1,37 +1,232 @@
 package main
 
 import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
     "log"
+    "net"
+    "os"
+    "path/filepath"
+    "time"
     "shei-deli/config"
+    "shei-deli/controllers"
+    "shei-deli/middleware"
+    "shei-deli/models"
+    "shei-deli/moderation"
+    "shei-deli/ranking"
     "shei-deli/routes"
+    "shei-deli/storage"
     "github.com/gin-gonic/gin"
 )
 
+// command is a `shei-deli <name>` subcommand
+type command struct {
+    name  string
+    usage string
+    help  string
+    run   func(cfg config.Config, args []string) error
+}
+
+// commands lists the subcommands in the order help shows them; it is filled
+// in by init because help refers back to it
+var commands []command
+
+func init() {
+    commands = []command{
+        {"serve", "serve [--addr host:port]", "Run the web server (the default)", runServe},
+        {"migrate", "migrate [up|down [steps]|status|create <name>]", "Manage schema migrations", runMigrate},
+        {"seed", "seed [--reset] [--fixtures set|file] [--recipes file]", "Create the admin account and load a fixture set", runSeed},
+        {"user", "user create|promote|deactivate ...", "Manage accounts", runUser},
+        {"recipe", "recipe export|import ...", "Move recipes in and out as JSON", runRecipe},
+        {"ratings", "ratings recompute", "Repair drifted rating aggregates and trending scores", runRatings},
+        {"media", "media migrate", "Move uploads from static/uploads into the media store", runMedia},
+        {"trash", "trash purge", "Permanently remove trash older than the retention window", runTrash},
+        {"help", "help", "Show this list", func(config.Config, []string) error {
+            printUsage(os.Stdout)
+            return nil
+        }},
+    }
+}
+
 func main() {
+    if err := run(os.Args[1:]); err != nil {
+        log.Fatal(err)
+    }
+}
+
+// run executes the subcommand named by args[0], serving when there is none
+func run(args []string) error {
+    if len(args) == 0 {
+        args = []string{"serve"}
+    }
+    if args[0] == "-h" || args[0] == "--help" {
+        args[0] = "help"
+    }
+
+    for _, cmd := range commands {
+        if cmd.name != args[0] {
+            continue
+        }
+        // help needs no settings, so it still works while they are invalid
+        if cmd.name == "help" {
+            return cmd.run(config.Config{}, args[1:])
+        }
+        // Settings come from CONFIG_FILE (or config.json) and the environment
+        cfg, err := config.Load()
+        if err != nil {
+            return fmt.Errorf("failed to load configuration: %w", err)
+        }
+        middleware.SetLogLevel(cfg.LogLevel)
+        return cmd.run(cfg, args[1:])
+    }
+    printUsage(os.Stderr)
+    return fmt.Errorf("unknown command %q", args[0])
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+    fmt.Fprintln(w, "Usage: shei-deli <command> [arguments]")
+    fmt.Fprintln(w)
+    for _, cmd := range commands {
+        fmt.Fprintf(w, "  %-50s %s\n", cmd.usage, cmd.help)
+    }
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments, which it returns in order
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+    fs.SetOutput(io.Discard)
+    var positional []string
+    for {
+        if err := fs.Parse(args); err != nil {
+            return nil, fmt.Errorf("%s: %w", fs.Name(), err)
+        }
+        args = fs.Args()
+        if len(args) == 0 {
+            return positional, nil
+        }
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+}
+
+// usageError reports a command invoked with the wrong arguments
+func usageError(usage string) error {
+    return errors.New("usage: shei-deli " + usage)
+}
+
+// configureMedia opens the media store selected by the media settings
+func configureMedia(cfg config.MediaConfig) (storage.BlobStore, error) {
+    if cfg.Backend == config.MediaS3 {
+        store, err := storage.NewS3Store(cfg.S3)
+        if err != nil {
+            return nil, fmt.Errorf("failed to configure media storage: %w", err)
+        }
+        return store, nil
+    }
+    return storage.NewLocalStore(cfg.Dir), nil
+}
+
+// configureModeration builds the content filter from the moderation settings
+func configureModeration(cfg config.ModerationConfig) (*moderation.Filter, error) {
+    filter := moderation.DefaultFilter()
+    if cfg.FilterFile != "" {
+        loaded, err := moderation.LoadFilter(cfg.FilterFile)
+        if err != nil {
+            return nil, fmt.Errorf("failed to configure moderation filter: %w", err)
+        }
+        filter = loaded
+    }
+    filter.RequireApproval = filter.RequireApproval || cfg.RequireApproval
+    return filter, nil
+}
+
+// runServe starts the web server and its background jobs
+func runServe(cfg config.Config, args []string) error {
+    fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+    fs.StringVar(&cfg.Server.Addr, "addr", cfg.Server.Addr, "address to listen on")
+    if rest, err := parseArgs(fs, args); err != nil {
+        return err
+    } else if len(rest) > 0 {
+        return usageError("serve [--addr host:port]")
+    }
+
     // Initialize database connection and run migrations
-    config.InitDatabase()
+    db, err := config.InitDatabase(cfg.Database)
+    if err != nil {
+        return err
+    }
+    defer config.CloseDatabase(db)
+
+    // Uploaded media lives in the store chosen by media.backend
+    store, err := configureMedia(cfg.Media)
+    if err != nil {
+        return err
+    }
+
+    // Submissions are screened by the filter in moderation.filter_file, or the built-in one
+    filter, err := configureModeration(cfg.Moderation)
+    if err != nil {
+        return err
+    }
 
     // Seed the database with initial data
-    config.SeedDatabase()
+    config.SeedDatabase(db, cfg)
+    // Backfill rating aggregates for databases created before they existed;
+    // a no-op once they are in sync
+    if fixed, err := models.RecomputeRatingAggregates(db); err != nil {
+        log.Printf("Failed to backfill rating aggregates: %v", err)
+    } else if fixed > 0 {
+        log.Printf("Backfilled rating aggregates for %d recipes", fixed)
+    }
+
+    // Reviews of recipes deleted before deletes cascaded join their recipe in the trash
+    if trashed, err := models.TrashOrphanedFeedback(db); err != nil {
+        log.Printf("Failed to trash orphaned feedback: %v", err)
+    } else if trashed > 0 {
+        log.Printf("Moved %d reviews of deleted recipes to the trash", trashed)
+    }
+
+    // Keep the materialized trending scores behind /featured up to date
+    stopTrending := ranking.StartTrendingRefresher(db, 10*time.Minute)
+    defer stopTrending()
+
+    // Permanently remove trash once it is past the retention window
+    stopPurger := controllers.StartTrashPurger(db, store, time.Hour)
+    defer stopPurger()
 
     // Setup Gin with template functions
-    gin.SetMode(gin.ReleaseMode) // Set to debug mode for development
+    gin.SetMode(cfg.Server.Mode)
 
     // Setup routes
-    router := routes.SetupRoutes()
+    routes.ConfigureAssets(cfg.Server.StaticDir, cfg.Server.ImagesDir)
+    services := controllers.NewServices(db, store, filter)
+    router := routes.SetupRoutes(services)
 
     // Set template functions
-    router.SetFuncMap(config.GetTemplateFunctions())
+    router.SetFuncMap(config.GetTemplateFunctions(services.Taxonomy))
 
     // Reload templates (for development)
-    router.LoadHTMLGlob("templates/*")
+    router.LoadHTMLGlob(filepath.Join(cfg.Server.TemplateDir, "*"))
 
     // Start the server
-    log.Println("Starting Shei-deli server on :8080...")
-    log.Println("Web interface: http://localhost:8080")
-    log.Println("API documentation: http://localhost:8080/api/v1/categories")
+    log.Printf("Starting Shei-deli server on %s...", cfg.Server.Addr)
+    log.Printf("Web interface: %s", serverURL(cfg.Server.Addr))
+    log.Printf("API documentation: %s/api/v1/categories", serverURL(cfg.Server.Addr))
+
+    if err := router.Run(cfg.Server.Addr); err != nil {
+        return fmt.Errorf("failed to start server: %w", err)
+    }
+    return nil
+}
 
-    if err := router.Run(":8080"); err != nil {
-        log.Fatal("Failed to start server:", err)
+// serverURL is the address browsers on this machine can reach addr at
+func serverURL(addr string) string {
+    host, port, err := net.SplitHostPort(addr)
+    if err != nil || host == "" || host == "0.0.0.0" || host == "::" {
+        host = "localhost"
     }
+    return "http://" + net.JoinHostPort(host, port)
 }
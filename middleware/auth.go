@@ -0,0 +1,134 @@
+package middleware
+
+import (
+    "strconv"
+    "shei-deli/apperrors"
+    "shei-deli/models"
+    "shei-deli/repository"
+    "github.com/gin-gonic/gin"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const (
+    moderatorKey = "moderator"
+    accountKey   = "account"
+)
+
+// RequireModerator authenticates the request with HTTP Basic credentials
+// (username or email and password) and only lets active moderators and admins
+// through. Browsers prompt for the credentials, so it also guards HTML pages.
+func RequireModerator(users repository.UserRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        user, ok := authenticate(c, users, "Shei-deli moderation")
+        if !ok {
+            return
+        }
+        if !user.CanModerate() {
+            c.Error(apperrors.Forbidden("Moderator access required"))
+            c.Abort()
+            return
+        }
+
+        c.Set(moderatorKey, user)
+        c.Next()
+    }
+}
+
+// RequireAdmin authenticates the request with HTTP Basic credentials and only
+// lets active admins through
+func RequireAdmin(users repository.UserRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        user, ok := authenticate(c, users, "Shei-deli administration")
+        if !ok {
+            return
+        }
+        if !user.IsActive || user.Role != models.RoleAdmin {
+            c.Error(apperrors.Forbidden("Admin access required"))
+            c.Abort()
+            return
+        }
+
+        c.Next()
+    }
+}
+
+// RequireAccountOwner authenticates the request with HTTP Basic credentials and
+// only lets through the user named by the :id parameter, or an admin.
+// Deactivated users still get through so they can reactivate, export or delete.
+func RequireAccountOwner(users repository.UserRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        user, ok := authenticate(c, users, "Shei-deli account")
+        if !ok {
+            return
+        }
+        if strconv.FormatUint(uint64(user.ID), 10) != c.Param("id") && !(user.IsActive && user.Role == models.RoleAdmin) {
+            c.Error(apperrors.Forbidden("You can only manage your own account"))
+            c.Abort()
+            return
+        }
+
+        c.Set(accountKey, user)
+        c.Next()
+    }
+}
+
+// RequireUser authenticates the request with HTTP Basic credentials and lets
+// any user through; handlers decide what the user may act on
+func RequireUser(users repository.UserRepository) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        user, ok := authenticate(c, users, "Shei-deli account")
+        if !ok {
+            return
+        }
+
+        c.Set(accountKey, user)
+        c.Next()
+    }
+}
+
+// GetModerator returns the moderator authenticated by RequireModerator
+func GetModerator(c *gin.Context) (models.User, bool) {
+    return getUser(c, moderatorKey)
+}
+
+// GetAccountUser returns the user authenticated by RequireAccountOwner or RequireUser
+func GetAccountUser(c *gin.Context) (models.User, bool) {
+    return getUser(c, accountKey)
+}
+
+func getUser(c *gin.Context, key string) (models.User, bool) {
+    value, ok := c.Get(key)
+    if !ok {
+        return models.User{}, false
+    }
+    user, ok := value.(models.User)
+    return user, ok
+}
+
+// authenticate checks HTTP Basic credentials against users and aborts with a
+// challenge for realm when they are missing or wrong
+func authenticate(c *gin.Context, users repository.UserRepository, realm string) (models.User, bool) {
+    login, password, ok := c.Request.BasicAuth()
+    if !ok {
+        unauthorized(c, realm, "Credentials required")
+        return models.User{}, false
+    }
+
+    user, err := users.FindByLogin(login)
+    if err != nil {
+        unauthorized(c, realm, "Invalid credentials")
+        return models.User{}, false
+    }
+    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+        unauthorized(c, realm, "Invalid credentials")
+        return models.User{}, false
+    }
+    useUserLocale(c, user)
+    return user, true
+}
+
+func unauthorized(c *gin.Context, realm, message string) {
+    c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+    c.Error(apperrors.Unauthorized(message))
+    c.Abort()
+}
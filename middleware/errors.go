@@ -0,0 +1,58 @@
+package middleware
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "shei-deli/apperrors"
+    "github.com/gin-gonic/gin"
+)
+
+// ErrorHandler renders errors recorded with c.Error as a consistent JSON envelope:
+//
+//	{"error": "Recipe not found", "code": "NOT_FOUND", "request_id": "...", "details": ...}
+func ErrorHandler() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        c.Next()
+
+        if len(c.Errors) == 0 || c.Writer.Written() {
+            return
+        }
+
+        appErr := toAppError(c.Errors.Last().Err)
+        requestID := GetRequestID(c)
+        if appErr.Status >= http.StatusInternalServerError || logEnabled(LevelDebug) {
+            log.Printf("[%s] %s %s: %v", requestID, c.Request.Method, c.Request.URL.Path, appErr)
+        }
+
+        body := gin.H{
+            "error":      appErr.Message,
+            "code":       appErr.Code,
+            "request_id": requestID,
+        }
+        if appErr.Details != nil {
+            body["details"] = appErr.Details
+        }
+        c.JSON(appErr.Status, body)
+    }
+}
+
+// Recovery converts panics into an internal error rendered through the error envelope
+func Recovery() gin.HandlerFunc {
+    return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+        log.Printf("[%s] panic recovered: %v", GetRequestID(c), recovered)
+        c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+            "error":      "Internal server error",
+            "code":       apperrors.CodeInternal,
+            "request_id": GetRequestID(c),
+        })
+    })
+}
+
+func toAppError(err error) *apperrors.Error {
+    var appErr *apperrors.Error
+    if errors.As(err, &appErr) {
+        return appErr
+    }
+    return apperrors.Internal("Internal server error", err)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+    "net/http"
+    "shei-deli/i18n"
+    "shei-deli/models"
+    "github.com/gin-gonic/gin"
+)
+
+// LocaleCookie remembers the language picked with ?lang= or at login
+const LocaleCookie = "lang"
+
+// localeCookieMaxAge keeps the language choice for a year
+const localeCookieMaxAge = 365 * 24 * 60 * 60
+
+const localeKey = "locale"
+
+// Locale picks the language for the request: ?lang= (which is also remembered
+// in a cookie), then the cookie, then Accept-Language, then the default.
+// Authenticated users' saved preference beats everything except ?lang=.
+func Locale() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        locale, ok := i18n.Match(c.Query("lang"))
+        if ok {
+            SetLocaleCookie(c, locale)
+        } else if cookie, err := c.Cookie(LocaleCookie); err == nil {
+            locale, ok = i18n.Match(cookie)
+        }
+        if !ok {
+            locale = i18n.Negotiate(c.GetHeader("Accept-Language"))
+        }
+
+        setLocale(c, locale)
+        c.Next()
+    }
+}
+
+// GetLocale returns the locale chosen for the current request
+func GetLocale(c *gin.Context) string {
+    if locale := c.GetString(localeKey); locale != "" {
+        return locale
+    }
+    return i18n.DefaultLocale
+}
+
+// SetLocaleCookie remembers locale for later requests from this browser
+func SetLocaleCookie(c *gin.Context, locale string) {
+    c.SetSameSite(http.SameSiteLaxMode)
+    c.SetCookie(LocaleCookie, locale, localeCookieMaxAge, "/", "", false, false)
+}
+
+// useUserLocale switches the request to an authenticated user's saved
+// language unless one was asked for explicitly with ?lang=
+func useUserLocale(c *gin.Context, user models.User) {
+    if _, explicit := i18n.Match(c.Query("lang")); explicit {
+        return
+    }
+    if locale, ok := i18n.Match(user.Locale); ok {
+        setLocale(c, locale)
+    }
+}
+
+func setLocale(c *gin.Context, locale string) {
+    c.Set(localeKey, locale)
+    c.Header("Content-Language", locale)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+    "github.com/gin-gonic/gin"
+)
+
+// Log levels accepted by SetLogLevel, from most to least verbose
+const (
+    LevelDebug = "debug"
+    LevelInfo  = "info"
+    LevelWarn  = "warn"
+    LevelError = "error"
+)
+
+var levelRanks = map[string]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2, LevelError: 3}
+
+var logLevel = levelRanks[LevelInfo]
+
+// SetLogLevel sets how much the middleware logs: requests are logged at info,
+// client errors at debug and server errors always. Unknown levels are ignored.
+func SetLogLevel(level string) {
+    if rank, ok := levelRanks[level]; ok {
+        logLevel = rank
+    }
+}
+
+func logEnabled(level string) bool {
+    return levelRanks[level] >= logLevel
+}
+
+// Logger logs each request when the log level is info or more verbose
+func Logger() gin.HandlerFunc {
+    logger := gin.Logger()
+    return func(c *gin.Context) {
+        if !logEnabled(LevelInfo) {
+            c.Next()
+            return
+        }
+        logger(c)
+    }
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header used to propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey = "request_id"
+
+// RequestID assigns every request an ID, reusing the caller's X-Request-ID when present
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.GetHeader(RequestIDHeader)
+        if id == "" || len(id) > 64 {
+            id = newRequestID()
+        }
+        c.Set(requestIDKey, id)
+        c.Header(RequestIDHeader, id)
+        c.Next()
+    }
+}
+
+// GetRequestID returns the ID assigned to the current request
+func GetRequestID(c *gin.Context) string {
+    return c.GetString(requestIDKey)
+}
+
+func newRequestID() string {
+    b := make([]byte, 8)
+    if _, err := rand.Read(b); err != nil {
+        return "unknown"
+    }
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,178 @@
+package migrations
+
+import (
+    "log"
+    "time"
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// The baseline creates the schema as it stood when versioned migrations
+// replaced AutoMigrate. The structs below are frozen copies of the models at
+// that point; change the schema in a new migration, never here. On a database
+// created by AutoMigrate the baseline adopts the existing tables and only adds
+// what they lack.
+
+type baselineRecipe struct {
+    gorm.Model
+    Title          string `gorm:"not null"`
+    Description    string
+    Ingredients    string `gorm:"type:text;not null"`
+    Instructions   string `gorm:"type:text;not null"`
+    Category       string `gorm:"not null"`
+    Cuisine        string `gorm:"index"`
+    Locale         string `gorm:"not null;default:en"`
+    PrepTime       int
+    CookTime       int
+    Servings       int
+    Difficulty     string
+    ImageURL       string
+    CardURL        string
+    ThumbnailURL   string
+    UserID         uint    `gorm:"not null"`
+    RatingSum      int     `gorm:"not null;default:0"`
+    RatingCount    int     `gorm:"not null;default:0;index"`
+    AverageRating  float64 `gorm:"not null;default:0;index"`
+    TrendingDay    float64 `gorm:"not null;default:0;index"`
+    TrendingWeek   float64 `gorm:"not null;default:0;index"`
+    TrendingMonth  float64 `gorm:"not null;default:0;index"`
+    Status         string  `gorm:"not null;default:approved;index"`
+    ModerationNote string
+    ReportCount    int  `gorm:"not null;default:0"`
+    APIRecipeID    *int `gorm:"default:null"`
+}
+
+func (baselineRecipe) TableName() string { return "recipes" }
+
+type baselineFeedback struct {
+    gorm.Model
+    RecipeID       uint   `gorm:"not null;uniqueIndex:idx_feedback_recipe_user"`
+    UserID         uint   `gorm:"not null;uniqueIndex:idx_feedback_recipe_user"`
+    Comment        string `gorm:"type:text"`
+    Rating         int    `gorm:"not null;check:rating >= 1 AND rating <= 5"`
+    HelpfulCount   int    `gorm:"not null;default:0"`
+    UnhelpfulCount int    `gorm:"not null;default:0"`
+    Status         string `gorm:"not null;default:approved;index"`
+    ModerationNote string
+    ReportCount    int `gorm:"not null;default:0"`
+}
+
+func (baselineFeedback) TableName() string { return "feedbacks" }
+
+type baselineFeedbackVote struct {
+    gorm.Model
+    FeedbackID uint `gorm:"not null;uniqueIndex:idx_vote_feedback_user"`
+    UserID     uint `gorm:"not null;uniqueIndex:idx_vote_feedback_user"`
+    Helpful    bool
+}
+
+func (baselineFeedbackVote) TableName() string { return "feedback_votes" }
+
+type baselineFeedbackReply struct {
+    gorm.Model
+    FeedbackID uint   `gorm:"not null;index"`
+    ParentID   *uint  `gorm:"index"`
+    UserID     uint   `gorm:"not null"`
+    Comment    string `gorm:"type:text;not null"`
+    Depth      int    `gorm:"not null;default:1"`
+}
+
+func (baselineFeedbackReply) TableName() string { return "feedback_replies" }
+
+type baselineFeedbackPhoto struct {
+    gorm.Model
+    FeedbackID   uint   `gorm:"not null;index"`
+    RecipeID     uint   `gorm:"not null;index"`
+    UserID       uint   `gorm:"not null"`
+    URL          string `gorm:"not null"`
+    ThumbnailURL string
+}
+
+func (baselineFeedbackPhoto) TableName() string { return "feedback_photos" }
+
+type baselineReport struct {
+    gorm.Model
+    ContentType string `gorm:"size:20;not null;uniqueIndex:idx_report_content_user"`
+    ContentID   uint   `gorm:"not null;uniqueIndex:idx_report_content_user"`
+    UserID      uint   `gorm:"not null;uniqueIndex:idx_report_content_user"`
+    Reason      string `gorm:"not null"`
+    Details     string `gorm:"type:text"`
+    Resolved    bool   `gorm:"not null;default:false;index"`
+}
+
+func (baselineReport) TableName() string { return "reports" }
+
+type baselineUser struct {
+    gorm.Model
+    Username     string `gorm:"size:64;uniqueIndex;not null"`
+    Email        string `gorm:"size:254;uniqueIndex;not null"`
+    Password     string `gorm:"not null"`
+    FirstName    string
+    LastName     string
+    Bio          string
+    AvatarURL    string
+    IsActive     bool   `gorm:"default:true"`
+    Role         string `gorm:"not null;default:user"`
+    Locale       string
+    JoinedAt     time.Time `gorm:"autoCreateTime"`
+    AnonymizedAt *time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineCategory struct {
+    gorm.Model
+    Key         string `gorm:"size:50;uniqueIndex;not null"`
+    ParentKey   string `gorm:"index"`
+    Name        string `gorm:"not null"`
+    Description string
+    Image       string
+    SortOrder   int `gorm:"not null;default:0;index"`
+}
+
+func (baselineCategory) TableName() string { return "categories" }
+
+type baselineCuisine struct {
+    gorm.Model
+    Key       string `gorm:"size:50;uniqueIndex;not null"`
+    Name      string `gorm:"not null"`
+    SortOrder int    `gorm:"not null;default:0;index"`
+}
+
+func (baselineCuisine) TableName() string { return "cuisines" }
+
+type baselineRecipeTranslation struct {
+    gorm.Model
+    RecipeID     uint   `gorm:"not null;uniqueIndex:idx_recipe_translation"`
+    Locale       string `gorm:"size:16;not null;uniqueIndex:idx_recipe_translation"`
+    Title        string `gorm:"not null"`
+    Description  string
+    Ingredients  string `gorm:"type:text"`
+    Instructions string `gorm:"type:text"`
+}
+
+func (baselineRecipeTranslation) TableName() string { return "recipe_translations" }
+
+func baselineTables() []interface{} {
+    return []interface{}{&baselineRecipe{}, &baselineFeedback{}, &baselineFeedbackVote{}, &baselineFeedbackReply{}, &baselineFeedbackPhoto{}, &baselineReport{}, &baselineUser{}, &baselineCategory{}, &baselineCuisine{}, &baselineRecipeTranslation{}}
+}
+
+func init() {
+    register(Migration{
+        Version: 20261019000000,
+        Name:    "baseline",
+        Up: func(tx *gorm.DB) error {
+            // Older databases may hold several reviews per user per recipe; keep the
+            // latest so the unique index can be created
+            if removed, err := models.DedupeFeedback(tx); err != nil {
+                return err
+            } else if removed > 0 {
+                log.Printf("Removed %d duplicate reviews", removed)
+            }
+            return tx.AutoMigrate(baselineTables()...)
+        },
+        Down: func(tx *gorm.DB) error {
+            return tx.Migrator().DropTable(baselineTables()...)
+        },
+    })
+}
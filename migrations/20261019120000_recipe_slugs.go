@@ -0,0 +1,31 @@
+package migrations
+
+import (
+    "gorm.io/gorm"
+)
+
+// recipeSlug is the recipes table as far as this migration is concerned
+type recipeSlug struct {
+    Slug *string `gorm:"size:120;uniqueIndex"`
+}
+
+func (recipeSlug) TableName() string { return "recipes" }
+
+func init() {
+    register(Migration{
+        Version: 20261019120000,
+        Name:    "recipe_slugs",
+        Up: func(tx *gorm.DB) error {
+            if err := tx.Migrator().AddColumn(&recipeSlug{}, "Slug"); err != nil {
+                return err
+            }
+            return tx.Migrator().CreateIndex(&recipeSlug{}, "Slug")
+        },
+        Down: func(tx *gorm.DB) error {
+            if err := tx.Migrator().DropIndex(&recipeSlug{}, "Slug"); err != nil {
+                return err
+            }
+            return tx.Migrator().DropColumn(&recipeSlug{}, "Slug")
+        },
+    })
+}
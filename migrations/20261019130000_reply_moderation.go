@@ -0,0 +1,41 @@
+package migrations
+
+import (
+    "gorm.io/gorm"
+)
+
+// replyModeration is the feedback_replies table as far as this migration is concerned
+type replyModeration struct {
+    Status         string `gorm:"not null;default:approved;index"`
+    ModerationNote string
+    ReportCount    int `gorm:"not null;default:0"`
+}
+
+func (replyModeration) TableName() string { return "feedback_replies" }
+
+func init() {
+    register(Migration{
+        Version: 20261019130000,
+        Name:    "reply_moderation",
+        Up: func(tx *gorm.DB) error {
+            // Existing replies stay visible; the column default approves them
+            for _, field := range []string{"Status", "ModerationNote", "ReportCount"} {
+                if err := tx.Migrator().AddColumn(&replyModeration{}, field); err != nil {
+                    return err
+                }
+            }
+            return tx.Migrator().CreateIndex(&replyModeration{}, "Status")
+        },
+        Down: func(tx *gorm.DB) error {
+            if err := tx.Migrator().DropIndex(&replyModeration{}, "Status"); err != nil {
+                return err
+            }
+            for _, field := range []string{"ReportCount", "ModerationNote", "Status"} {
+                if err := tx.Migrator().DropColumn(&replyModeration{}, field); err != nil {
+                    return err
+                }
+            }
+            return nil
+        },
+    })
+}
@@ -0,0 +1,224 @@
+package migrations
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "time"
+    "gorm.io/gorm"
+)
+
+// VersionLayout formats the timestamp that versions new migrations
+const VersionLayout = "20060102150405"
+
+// Migration is one versioned change to the schema or its data. Up applies it
+// and Down reverts it; a nil Down marks the migration irreversible.
+type Migration struct {
+    Version int64
+    Name    string
+    Up      func(tx *gorm.DB) error
+    Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records a migration applied to the database
+type SchemaMigration struct {
+    Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+    Name      string    `gorm:"not null"`
+    AppliedAt time.Time `gorm:"not null"`
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+    Version   int64      `json:"version"`
+    Name      string     `json:"name"`
+    AppliedAt *time.Time `json:"applied_at"` // nil while pending
+    Unknown   bool       `json:"unknown,omitempty"` // Applied but not part of this build
+}
+
+var registry []Migration
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// register adds a migration; each migration file calls it from init
+func register(m Migration) {
+    for _, existing := range registry {
+        if existing.Version == m.Version {
+            panic(fmt.Sprintf("migration %d registered twice", m.Version))
+        }
+    }
+    registry = append(registry, m)
+    sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+// All returns every known migration, oldest first
+func All() []Migration {
+    return append([]Migration(nil), registry...)
+}
+
+// applied returns the applied migrations keyed by version
+func applied(db *gorm.DB) (map[int64]SchemaMigration, error) {
+    if !db.Migrator().HasTable(&SchemaMigration{}) {
+        if err := db.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+            return nil, fmt.Errorf("create schema_migrations: %w", err)
+        }
+    }
+    var rows []SchemaMigration
+    if err := db.Order("version").Find(&rows).Error; err != nil {
+        return nil, err
+    }
+    done := make(map[int64]SchemaMigration, len(rows))
+    for _, row := range rows {
+        done[row.Version] = row
+    }
+    return done, nil
+}
+
+// Pending returns the migrations that have not been applied, oldest first
+func Pending(db *gorm.DB) ([]Migration, error) {
+    done, err := applied(db)
+    if err != nil {
+        return nil, err
+    }
+    var pending []Migration
+    for _, m := range registry {
+        if _, ok := done[m.Version]; !ok {
+            pending = append(pending, m)
+        }
+    }
+    return pending, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones it applied
+func Up(db *gorm.DB) ([]Migration, error) {
+    pending, err := Pending(db)
+    if err != nil {
+        return nil, err
+    }
+    var done []Migration
+    for _, m := range pending {
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if err := m.Up(tx); err != nil {
+                return err
+            }
+            return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+        })
+        if err != nil {
+            return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+        }
+        done = append(done, m)
+    }
+    return done, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns
+// the ones it reverted
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+    done, err := applied(db)
+    if err != nil {
+        return nil, err
+    }
+    known := make(map[int64]Migration, len(registry))
+    for _, m := range registry {
+        known[m.Version] = m
+    }
+    versions := make([]int64, 0, len(done))
+    for version := range done {
+        versions = append(versions, version)
+    }
+    sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+    var reverted []Migration
+    for _, version := range versions {
+        if len(reverted) == steps {
+            break
+        }
+        m, ok := known[version]
+        if !ok {
+            return reverted, fmt.Errorf("migration %d_%s is not part of this build", version, done[version].Name)
+        }
+        if m.Down == nil {
+            return reverted, fmt.Errorf("migration %d_%s is irreversible", m.Version, m.Name)
+        }
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if err := m.Down(tx); err != nil {
+                return err
+            }
+            return tx.Delete(&SchemaMigration{}, m.Version).Error
+        })
+        if err != nil {
+            return reverted, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+        }
+        reverted = append(reverted, m)
+    }
+    return reverted, nil
+}
+
+// StatusOf lists every known migration with when it was applied, followed by
+// any applied migrations this build doesn't know about
+func StatusOf(db *gorm.DB) ([]Status, error) {
+    done, err := applied(db)
+    if err != nil {
+        return nil, err
+    }
+    statuses := make([]Status, 0, len(registry))
+    for _, m := range registry {
+        status := Status{Version: m.Version, Name: m.Name}
+        if row, ok := done[m.Version]; ok {
+            appliedAt := row.AppliedAt
+            status.AppliedAt = &appliedAt
+            delete(done, m.Version)
+        }
+        statuses = append(statuses, status)
+    }
+    for _, row := range done {
+        appliedAt := row.AppliedAt
+        statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Unknown: true})
+    }
+    sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+    return statuses, nil
+}
+
+// Create writes an empty migration named name into dir, versioned by now, and
+// returns its path
+func Create(dir, name string, now time.Time) (string, error) {
+    if !namePattern.MatchString(name) {
+        return "", errors.New("migration names may only contain lowercase letters, numbers and single underscores")
+    }
+    version := now.UTC().Format(VersionLayout)
+    path := filepath.Join(dir, version+"_"+name+".go")
+    source := fmt.Sprintf(template, version, name)
+
+    file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+    if err != nil {
+        return "", err
+    }
+    defer file.Close()
+    if _, err := file.WriteString(source); err != nil {
+        return "", err
+    }
+    return path, nil
+}
+
+const template = `package migrations
+
+import (
+    "gorm.io/gorm"
+)
+
+func init() {
+    register(Migration{
+        Version: %s,
+        Name:    %q,
+        Up: func(tx *gorm.DB) error {
+            return nil
+        },
+        Down: func(tx *gorm.DB) error {
+            return nil
+        },
+    })
+}
+`
@@ -0,0 +1,113 @@
+package models
+
+import (
+    "fmt"
+    "time"
+    "gorm.io/gorm"
+)
+
+// Account deletion modes: keep the user's content under an anonymous name, or
+// remove it along with the account
+const (
+    DeleteAnonymize = "anonymize"
+    DeleteRemove    = "remove"
+)
+
+// DeletedReplyComment replaces the text of removed replies that others answered
+const DeletedReplyComment = "[deleted]"
+
+// SetUserActive deactivates or reactivates an account
+func SetUserActive(tx *gorm.DB, user *User, active bool) error {
+    if err := tx.Model(user).Update("is_active", active).Error; err != nil {
+        return err
+    }
+    user.IsActive = active
+    return nil
+}
+
+// AnonymizeUser scrubs every piece of personal data from an account and closes
+// it. The row is kept so content, votes and reports still point at a user.
+func AnonymizeUser(tx *gorm.DB, user *User, at time.Time) error {
+    updates := map[string]interface{}{
+        "username":      fmt.Sprintf("deleted-user-%d", user.ID),
+        "email":         fmt.Sprintf("deleted-user-%d@invalid", user.ID),
+        "password":      "", // Never matches a bcrypt hash, so the account cannot sign in
+        "first_name":    "",
+        "last_name":     "",
+        "bio":           "",
+        "avatar_url":    "",
+        "is_active":     false,
+        "anonymized_at": at,
+    }
+    if err := tx.Model(user).Updates(updates).Error; err != nil {
+        return err
+    }
+    return tx.First(user, user.ID).Error
+}
+
+// RemoveUserContent hard-deletes a user's recipes (with every review on them),
+// the user's reviews of other recipes and the user's replies. Replies that
+// others have answered are blanked instead so the thread stays intact. The
+// caller removes MediaURLs from the media store once the transaction commits.
+func RemoveUserContent(tx *gorm.DB, userID uint) (PurgeResult, error) {
+    var recipeIDs []uint
+    if err := tx.Unscoped().Model(&Recipe{}).Where("user_id = ?", userID).Pluck("id", &recipeIDs).Error; err != nil {
+        return PurgeResult{}, err
+    }
+    ownRecipe := make(map[uint]bool, len(recipeIDs))
+    for _, id := range recipeIDs {
+        ownRecipe[id] = true
+    }
+
+    var feedbacks []Feedback
+    if err := tx.Unscoped().Where("user_id = ? OR recipe_id IN ?", userID, recipeIDs).Find(&feedbacks).Error; err != nil {
+        return PurgeResult{}, err
+    }
+    feedbackIDs := make([]uint, len(feedbacks))
+    for i, f := range feedbacks {
+        feedbackIDs[i] = f.ID
+        // Reviews of other people's recipes come out of those recipes' ratings
+        if !ownRecipe[f.RecipeID] {
+            sum, count := RatingContribution(f)
+            if err := AdjustRatingAggregates(tx, f.RecipeID, -sum, -count); err != nil {
+                return PurgeResult{}, err
+            }
+        }
+    }
+
+    result, err := purgeRows(tx, recipeIDs, feedbackIDs)
+    if err != nil {
+        return result, err
+    }
+    return result, removeUserReplies(tx, userID)
+}
+
+// removeUserReplies deletes a user's remaining replies, blanking the ones
+// that have answers
+func removeUserReplies(tx *gorm.DB, userID uint) error {
+    // Read the answered IDs up front; MySQL can't update a table a subquery reads
+    own := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&FeedbackReply{}).Where("user_id = ?", userID).Select("id")
+    var answered []uint
+    if err := tx.Unscoped().Model(&FeedbackReply{}).Where("parent_id IN (?)", own).Distinct().Pluck("parent_id", &answered).Error; err != nil {
+        return err
+    }
+
+    remove := tx.Unscoped().Model(&FeedbackReply{}).Where("user_id = ?", userID)
+    if len(answered) > 0 {
+        err := tx.Unscoped().Model(&FeedbackReply{}).
+            Where("id IN ?", answered).
+            Update("comment", DeletedReplyComment).Error
+        if err != nil {
+            return err
+        }
+        remove = remove.Where("id NOT IN ?", answered)
+    }
+    var removed []uint
+    if err := remove.Pluck("id", &removed).Error; err != nil {
+        return err
+    }
+    if err := tx.Unscoped().Where("content_type = ? AND content_id IN ?", ContentReply, removed).Delete(&Report{}).Error; err != nil {
+        return err
+    }
+    return tx.Unscoped().Where("id IN ?", removed).Delete(&FeedbackReply{}).Error
+}
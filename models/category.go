@@ -0,0 +1,211 @@
+package models
+
+import (
+    "strings"
+    "shei-deli/i18n"
+    "gorm.io/gorm"
+)
+
+// defaultCategoryImage is shown for recipes whose category has no image
+const defaultCategoryImage = "vegan.jpeg"
+
+// Category is a recipe category; recipes reference it by Key. Categories
+// nest through ParentKey, and listing a category includes its subcategories.
+type Category struct {
+    gorm.Model
+    Key           string     `json:"key" gorm:"size:50;uniqueIndex;not null"`
+    ParentKey     string     `json:"parent_key,omitempty" gorm:"index"` // Empty for top-level categories
+    Name          string     `json:"name" gorm:"not null"`
+    Description   string     `json:"description"`
+    Image         string     `json:"image"` // File name under /images, or an absolute URL
+    SortOrder     int        `json:"sort_order" gorm:"not null;default:0;index"`
+    Subcategories []Category `json:"subcategories,omitempty" gorm:"-"`
+}
+
+// ImageURL returns the path the category's image is served from
+func (c Category) ImageURL() string {
+    return categoryImageURL(c.Image)
+}
+
+func categoryImageURL(image string) string {
+    if image == "" {
+        image = defaultCategoryImage
+    }
+    if strings.HasPrefix(image, "/") || strings.Contains(image, "://") {
+        return image
+    }
+    return "/images/" + image
+}
+
+// Localized returns the category, and its subcategories, with the name and
+// description translated into locale. Text without a catalog entry, such as
+// categories added by admins, is kept as stored.
+func (c Category) Localized(locale string) Category {
+    if name, ok := i18n.Lookup(locale, "category."+c.Key+".name"); ok {
+        c.Name = name
+    }
+    if description, ok := i18n.Lookup(locale, "category."+c.Key+".description"); ok {
+        c.Description = description
+    }
+    if c.Subcategories != nil {
+        c.Subcategories = LocalizeCategories(c.Subcategories, locale)
+    }
+    return c
+}
+
+// LocalizeCategories returns a translated copy of categories
+func LocalizeCategories(categories []Category, locale string) []Category {
+    localized := make([]Category, len(categories))
+    for i, category := range categories {
+        localized[i] = category.Localized(locale)
+    }
+    return localized
+}
+
+// DefaultCategories are the categories every installation starts with
+func DefaultCategories() []Category {
+    return []Category{
+        {Key: string(PlantBasedMeals), Name: "Plant-Based Meals", Description: "Vegan/vegetarian options (no animal products)", Image: "vegan.jpeg"},
+        {Key: string(KidsMeals), Name: "Kids' Meals", Description: "Fun, simple, and nutritious meals for children", Image: "kids-meals.jpeg"},
+        {Key: string(LightMeals), Name: "Light Meals (Weight Loss)", Description: "Low-calorie, balanced recipes", Image: "light-meals.jpeg"},
+        {Key: string(HeartyMeals), Name: "Hearty Meals (Weight Gain)", Description: "High-calorie, energy-packed recipes", Image: "hearty-meals.jpeg"},
+        {Key: string(Stews), Name: "Stews", Description: "Slow-cooked stews with meat, vegetables, seafood and more", Image: "stews.jpeg"},
+        {Key: string(MeatStews), ParentKey: string(Stews), Name: "Meat Stews", Description: "Beef, chicken, goat, lamb, and other meat-based stews", Image: "stews.jpeg"},
+        {Key: string(VeggieStews), ParentKey: string(Stews), Name: "Veggie Stews", Description: "Lentil, bean, mushroom, and vegetable stews", Image: "vegetable-stews.jpeg"},
+        {Key: string(SeafoodStews), ParentKey: string(Stews), Name: "Seafood & Fish Stews", Description: "Fish stews, seafood mixes, and ocean-inspired flavors", Image: "fish&sea-food.jpeg"},
+        {Key: string(FusionStews), ParentKey: string(Stews), Name: "Fusion Stews", Description: "Cultural and traditional varieties (e.g., goulash, curries)", Image: "fusion.jpeg"},
+        {Key: string(Soups), Name: "Soups", Description: "Warm, comforting soups", Image: "soups.jpeg"},
+        {Key: string(Drinks), Name: "Drinks", Description: "Smoothies, juices, teas, and other beverages", Image: "drinks&smoothies.jpeg"},
+        {Key: string(Pastries), Name: "Pastries", Description: "Baked goods such as cakes, cookies, pies, and breads", Image: "pastries.jpeg"},
+    }
+}
+
+// SeedCategories creates any default categories that are missing. Existing
+// rows are left alone so edits made by admins survive restarts, except that
+// when a default parent is created, its default subcategories that have no
+// parent yet are moved under it.
+func SeedCategories(db *gorm.DB) (int, error) {
+    created := make(map[string]bool)
+    for i, def := range DefaultCategories() {
+        category := def
+        category.SortOrder = (i + 1) * 10
+        result := db.Where(&Category{Key: category.Key}).FirstOrCreate(&category)
+        if result.Error != nil {
+            return len(created), result.Error
+        }
+        if result.RowsAffected > 0 {
+            created[category.Key] = true
+        }
+
+        if def.ParentKey != "" && created[def.ParentKey] && category.ParentKey == "" {
+            if err := db.Model(&category).Update("parent_key", def.ParentKey).Error; err != nil {
+                return len(created), err
+            }
+        }
+    }
+    return len(created), nil
+}
+
+// ListCategories returns all categories in display order
+func ListCategories(db *gorm.DB) ([]Category, error) {
+    var categories []Category
+    err := db.Order("sort_order, id").Find(&categories).Error
+    return categories, err
+}
+
+// CategoryTree nests categories under their parents, keeping their order, and
+// returns the top-level ones. Categories whose parent is missing count as top-level.
+func CategoryTree(categories []Category) []Category {
+    known := make(map[string]bool, len(categories))
+    children := make(map[string][]Category)
+    for _, category := range categories {
+        known[category.Key] = true
+    }
+    for _, category := range categories {
+        if category.ParentKey != "" && known[category.ParentKey] {
+            children[category.ParentKey] = append(children[category.ParentKey], category)
+        }
+    }
+
+    var attach func(category Category, seen map[string]bool) Category
+    attach = func(category Category, seen map[string]bool) Category {
+        seen[category.Key] = true
+        for _, child := range children[category.Key] {
+            if !seen[child.Key] {
+                category.Subcategories = append(category.Subcategories, attach(child, seen))
+            }
+        }
+        return category
+    }
+
+    var roots []Category
+    seen := make(map[string]bool)
+    for _, category := range categories {
+        if category.ParentKey == "" || !known[category.ParentKey] {
+            roots = append(roots, attach(category, seen))
+        }
+    }
+    return roots
+}
+
+// CategoryKeysWithDescendants returns key followed by the keys of all its
+// subcategories at any depth
+func CategoryKeysWithDescendants(db *gorm.DB, key string) ([]string, error) {
+    keys := []string{key}
+    seen := map[string]bool{key: true}
+    for level := []string{key}; len(level) > 0; {
+        var children []string
+        if err := db.Model(&Category{}).Where("parent_key IN ?", level).Order("sort_order, id").Pluck("key", &children).Error; err != nil {
+            return nil, err
+        }
+        level = level[:0]
+        for _, child := range children {
+            if !seen[child] {
+                seen[child] = true
+                keys = append(keys, child)
+                level = append(level, child)
+            }
+        }
+    }
+    return keys, nil
+}
+
+// FindCategory loads a category by key
+func FindCategory(db *gorm.DB, key string) (Category, error) {
+    var category Category
+    if key == "" {
+        return category, gorm.ErrRecordNotFound
+    }
+    err := db.Where(&Category{Key: key}).First(&category).Error
+    return category, err
+}
+
+// IsValidCategory checks if a category with this key exists
+func IsValidCategory(db *gorm.DB, key string) bool {
+    if key == "" {
+        return false
+    }
+    var count int64
+    db.Model(&Category{}).Where(&Category{Key: key}).Count(&count)
+    return count > 0
+}
+
+// CategoryImageURL returns the image shown for recipes in a category that
+// have no image of their own
+func CategoryImageURL(db *gorm.DB, key RecipeCategory) string {
+    category, err := FindCategory(db, string(key))
+    if err != nil {
+        return categoryImageURL("")
+    }
+    return category.ImageURL()
+}
+
+// LocalizedCategoryName returns a category's display name in locale, or the
+// key if it is unknown
+func LocalizedCategoryName(db *gorm.DB, key RecipeCategory, locale string) string {
+    category, err := FindCategory(db, string(key))
+    if err != nil {
+        return string(key)
+    }
+    return category.Localized(locale).Name
+}
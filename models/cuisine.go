@@ -0,0 +1,81 @@
+package models
+
+import (
+    "gorm.io/gorm"
+)
+
+// Cuisine is a culinary tradition a recipe can be tagged with, independent of
+// its category; recipes reference it by Key
+type Cuisine struct {
+    gorm.Model
+    Key       string `json:"key" gorm:"size:50;uniqueIndex;not null"`
+    Name      string `json:"name" gorm:"not null"` // Also the cuisine/area name used by external recipe APIs
+    SortOrder int    `json:"sort_order" gorm:"not null;default:0;index"`
+}
+
+// DefaultCuisines are the cuisines every installation starts with
+func DefaultCuisines() []Cuisine {
+    return []Cuisine{
+        {Key: "african", Name: "African"},
+        {Key: "american", Name: "American"},
+        {Key: "caribbean", Name: "Caribbean"},
+        {Key: "chinese", Name: "Chinese"},
+        {Key: "french", Name: "French"},
+        {Key: "greek", Name: "Greek"},
+        {Key: "indian", Name: "Indian"},
+        {Key: "italian", Name: "Italian"},
+        {Key: "japanese", Name: "Japanese"},
+        {Key: "korean", Name: "Korean"},
+        {Key: "mediterranean", Name: "Mediterranean"},
+        {Key: "mexican", Name: "Mexican"},
+        {Key: "middle_eastern", Name: "Middle Eastern"},
+        {Key: "thai", Name: "Thai"},
+        {Key: "vietnamese", Name: "Vietnamese"},
+    }
+}
+
+// SeedCuisines creates any default cuisines that are missing
+func SeedCuisines(db *gorm.DB) (int, error) {
+    created := 0
+    for i, cuisine := range DefaultCuisines() {
+        cuisine.SortOrder = (i + 1) * 10
+        result := db.Where(&Cuisine{Key: cuisine.Key}).FirstOrCreate(&cuisine)
+        if result.Error != nil {
+            return created, result.Error
+        }
+        created += int(result.RowsAffected)
+    }
+    return created, nil
+}
+
+// ListCuisines returns all cuisines in display order
+func ListCuisines(db *gorm.DB) ([]Cuisine, error) {
+    var cuisines []Cuisine
+    err := db.Order("sort_order, id").Find(&cuisines).Error
+    return cuisines, err
+}
+
+// FindCuisine loads a cuisine by key
+func FindCuisine(db *gorm.DB, key string) (Cuisine, error) {
+    var cuisine Cuisine
+    if key == "" {
+        return cuisine, gorm.ErrRecordNotFound
+    }
+    err := db.Where(&Cuisine{Key: key}).First(&cuisine).Error
+    return cuisine, err
+}
+
+// CuisineName returns a cuisine's display name, or the key if it is unknown
+func CuisineName(db *gorm.DB, key string) string {
+    cuisine, err := FindCuisine(db, key)
+    if err != nil {
+        return key
+    }
+    return cuisine.Name
+}
+
+// IsValidCuisine checks if a cuisine with this key exists
+func IsValidCuisine(db *gorm.DB, key string) bool {
+    _, err := FindCuisine(db, key)
+    return err == nil
+}
@@ -8,11 +8,17 @@ import (
 // Feedback model stores recipe feedback and ratings
 type Feedback struct {
     gorm.Model
-    RecipeID    uint      `json:"recipe_id" gorm:"not null"`
-    UserID      uint      `json:"user_id" gorm:"not null"`
+    RecipeID    uint      `json:"recipe_id" gorm:"not null;uniqueIndex:idx_feedback_recipe_user"` // One review per user per recipe
+    UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_feedback_recipe_user"`
     Comment     string    `json:"comment" gorm:"type:text"`
     Rating      int       `json:"rating" gorm:"not null;check:rating >= 1 AND rating <= 5"`
-    IsHelpful   bool      `json:"is_helpful" gorm:"default:false"`
+    HelpfulCount   int    `json:"helpful_count" gorm:"not null;default:0"`   // Maintained from FeedbackVote rows
+    UnhelpfulCount int    `json:"unhelpful_count" gorm:"not null;default:0"`
+    Status      ModerationStatus `json:"status" gorm:"not null;default:approved;index"` // Only approved reviews count towards ratings
+    ModerationNote string `json:"moderation_note,omitempty"`
+    ReportCount int       `json:"report_count" gorm:"not null;default:0"`
+    Replies     []FeedbackReply `json:"replies,omitempty" gorm:"-"` // Filled by LoadReplyThreads
+    Photos      []FeedbackPhoto `json:"photos,omitempty" gorm:"foreignKey:FeedbackID"`
     CreatedAt   time.Time `json:"created_at"`
 
     // Relationships
@@ -23,4 +29,33 @@ type Feedback struct {
 // IsValidRating checks if the rating is within valid range (1-5)
 func (f *Feedback) IsValidRating() bool {
     return f.Rating >= 1 && f.Rating <= 5
-}
\ No newline at end of file
+}
+
+// DedupeFeedback hard-deletes all but the latest review per user per recipe so
+// the unique (recipe_id, user_id) index can be created on older databases.
+// Live reviews win over soft-deleted ones.
+func DedupeFeedback(db *gorm.DB) (int64, error) {
+    if !db.Migrator().HasTable(&Feedback{}) {
+        return 0, nil
+    }
+
+    // MySQL can't delete from a table a subquery reads, so the IDs are read
+    // through derived tables, which it materializes first
+    var removed int64
+    err := db.Transaction(func(tx *gorm.DB) error {
+        result := tx.Exec(`DELETE FROM feedbacks WHERE id IN (SELECT id FROM (
+            SELECT DISTINCT dead.id FROM feedbacks AS dead JOIN feedbacks AS live
+            ON live.recipe_id = dead.recipe_id AND live.user_id = dead.user_id
+            WHERE dead.deleted_at IS NOT NULL AND live.deleted_at IS NULL) AS shadowed)`)
+        if result.Error != nil {
+            return result.Error
+        }
+        removed = result.RowsAffected
+
+        result = tx.Exec(`DELETE FROM feedbacks WHERE id NOT IN (SELECT id FROM (
+            SELECT MAX(id) AS id FROM feedbacks GROUP BY recipe_id, user_id) AS latest)`)
+        removed += result.RowsAffected
+        return result.Error
+    })
+    return removed, err
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+    "gorm.io/gorm"
+)
+
+// FeedbackPhoto is a photo of the cooked dish attached to a review
+type FeedbackPhoto struct {
+    gorm.Model
+    FeedbackID  uint      `json:"feedback_id" gorm:"not null;index"`
+    RecipeID    uint      `json:"recipe_id" gorm:"not null;index"` // Copied from the review for recipe galleries
+    UserID      uint      `json:"user_id" gorm:"not null"`
+    URL          string   `json:"url" gorm:"not null"`
+    ThumbnailURL string   `json:"thumbnail_url"`
+
+    // Relationships
+    User        User      `json:"user" gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+    "gorm.io/gorm"
+)
+
+// MaxReplyDepth is how deeply replies may nest below a review; a direct reply has depth 1
+const MaxReplyDepth = 3
+
+// FeedbackReply is a comment in the discussion thread under a review
+type FeedbackReply struct {
+    gorm.Model
+    FeedbackID  uint      `json:"feedback_id" gorm:"not null;index"`  // Review the thread belongs to
+    ParentID    *uint     `json:"parent_id" gorm:"index"`             // Reply being answered; nil for direct replies
+    UserID      uint      `json:"user_id" gorm:"not null"`
+    Comment     string    `json:"comment" gorm:"type:text;not null"`
+    Depth       int       `json:"depth" gorm:"not null;default:1"`
+    Status      ModerationStatus `json:"status" gorm:"not null;default:approved;index"` // Only approved replies are shown in threads
+    ModerationNote string `json:"moderation_note,omitempty"` // Why the reply was held or rejected
+    ReportCount int       `json:"report_count" gorm:"not null;default:0"` // Open user reports
+    IsAuthor    bool      `json:"is_author" gorm:"-"`                 // Written by the recipe's author
+    Replies     []FeedbackReply `json:"replies" gorm:"-"`
+
+    // Relationships
+    User        User      `json:"user" gorm:"foreignKey:UserID"`
+    Feedback    *Feedback `json:"-" gorm:"foreignKey:FeedbackID"` // Loaded for the moderation dashboard
+}
+
+// LoadReplyThreads attaches each review's approved replies as nested threads,
+// oldest first, and marks replies written by the author of the reviewed
+// recipe. Answers to a hidden reply are hidden with it.
+func LoadReplyThreads(db *gorm.DB, feedbacks []Feedback) error {
+    if len(feedbacks) == 0 {
+        return nil
+    }
+
+    feedbackIDs := make([]uint, len(feedbacks))
+    recipeIDs := make([]uint, len(feedbacks))
+    for i, f := range feedbacks {
+        feedbackIDs[i] = f.ID
+        recipeIDs[i] = f.RecipeID
+    }
+
+    var recipes []Recipe
+    if err := db.Select("id, user_id").Where("id IN ?", recipeIDs).Find(&recipes).Error; err != nil {
+        return err
+    }
+    authors := make(map[uint]uint, len(recipes))
+    for _, r := range recipes {
+        authors[r.ID] = r.UserID
+    }
+
+    var replies []FeedbackReply
+    if err := db.Preload("User").Scopes(Approved("feedback_replies")).Where("feedback_id IN ?", feedbackIDs).Order("id ASC").Find(&replies).Error; err != nil {
+        return err
+    }
+
+    // Group by parent; direct replies are keyed by their review under parent 0
+    type key struct{ feedbackID, parentID uint }
+    children := make(map[key][]FeedbackReply)
+    for _, r := range replies {
+        k := key{feedbackID: r.FeedbackID}
+        if r.ParentID != nil {
+            k.parentID = *r.ParentID
+        }
+        children[k] = append(children[k], r)
+    }
+
+    var build func(feedbackID, parentID, authorID uint) []FeedbackReply
+    build = func(feedbackID, parentID, authorID uint) []FeedbackReply {
+        thread := children[key{feedbackID, parentID}]
+        for i := range thread {
+            thread[i].IsAuthor = thread[i].UserID == authorID
+            thread[i].Replies = build(feedbackID, thread[i].ID, authorID)
+        }
+        return thread
+    }
+    for i := range feedbacks {
+        feedbacks[i].Replies = build(feedbacks[i].ID, 0, authors[feedbacks[i].RecipeID])
+    }
+    return nil
+}
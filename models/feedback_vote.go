@@ -0,0 +1,81 @@
+package models
+
+import (
+    "errors"
+    "gorm.io/gorm"
+)
+
+// FeedbackVote records whether a user found someone else's review helpful
+type FeedbackVote struct {
+    gorm.Model
+    FeedbackID  uint      `json:"feedback_id" gorm:"not null;uniqueIndex:idx_vote_feedback_user"` // One vote per user per review
+    UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_vote_feedback_user"`
+    Helpful     bool      `json:"helpful"`
+
+    // Relationships
+    Feedback    Feedback  `json:"-" gorm:"foreignKey:FeedbackID"`
+    User        User      `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// AdjustVoteCounts applies a change in helpful and unhelpful votes to a review's
+// denormalized counts. Call it inside the same transaction that writes the vote.
+func AdjustVoteCounts(tx *gorm.DB, feedbackID uint, helpfulDelta, unhelpfulDelta int) error {
+    return tx.Model(&Feedback{}).Where("id = ?", feedbackID).Updates(map[string]interface{}{
+        "helpful_count":   gorm.Expr("helpful_count + ?", helpfulDelta),
+        "unhelpful_count": gorm.Expr("unhelpful_count + ?", unhelpfulDelta),
+    }).Error
+}
+
+// voteDeltas returns the count changes for a single vote
+func voteDeltas(helpful bool, sign int) (int, int) {
+    if helpful {
+        return sign, 0
+    }
+    return 0, sign
+}
+
+// CastVote records or changes a user's vote on a review and keeps the review's
+// counts in step. Re-casting the same vote is a no-op.
+func CastVote(tx *gorm.DB, feedbackID, userID uint, helpful bool) error {
+    var vote FeedbackVote
+    err := tx.Where("feedback_id = ? AND user_id = ?", feedbackID, userID).First(&vote).Error
+    switch {
+    case errors.Is(err, gorm.ErrRecordNotFound):
+        vote = FeedbackVote{FeedbackID: feedbackID, UserID: userID, Helpful: helpful}
+        if err := tx.Create(&vote).Error; err != nil {
+            return err
+        }
+        h, u := voteDeltas(helpful, 1)
+        return AdjustVoteCounts(tx, feedbackID, h, u)
+    case err != nil:
+        return err
+    case vote.Helpful == helpful:
+        return nil
+    }
+
+    if err := tx.Model(&vote).Update("helpful", helpful).Error; err != nil {
+        return err
+    }
+    h, u := voteDeltas(helpful, 1)
+    oh, ou := voteDeltas(!helpful, -1)
+    return AdjustVoteCounts(tx, feedbackID, h+oh, u+ou)
+}
+
+// RetractVote removes a user's vote on a review, if any
+func RetractVote(tx *gorm.DB, feedbackID, userID uint) error {
+    var vote FeedbackVote
+    err := tx.Where("feedback_id = ? AND user_id = ?", feedbackID, userID).First(&vote).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    // Hard delete so the user can vote again without hitting the unique index
+    if err := tx.Unscoped().Delete(&vote).Error; err != nil {
+        return err
+    }
+    h, u := voteDeltas(vote.Helpful, -1)
+    return AdjustVoteCounts(tx, feedbackID, h, u)
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+    "context"
+    "errors"
+    "log"
+    "mime"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "shei-deli/storage"
+    "gorm.io/gorm"
+)
+
+// LegacyUploadPrefix is the URL path uploads were served under before media
+// moved to a BlobStore
+const LegacyUploadPrefix = "/static/uploads/"
+
+// mediaColumns are the columns holding uploaded media URLs
+var mediaColumns = []struct {
+    model  interface{}
+    column string
+}{
+    {&Recipe{}, "image_url"},
+    {&Recipe{}, "card_url"},
+    {&Recipe{}, "thumbnail_url"},
+    {&FeedbackPhoto{}, "url"},
+    {&FeedbackPhoto{}, "thumbnail_url"},
+}
+
+// MigrateLegacyMedia copies files referenced by /static/uploads/ URLs from
+// legacyDir into store and rewrites those URLs to their /media/ equivalents,
+// including on soft-deleted rows. Files that no longer exist are logged and
+// left alone. It is safe to run repeatedly and returns how many URLs changed.
+func MigrateLegacyMedia(db *gorm.DB, store storage.BlobStore, legacyDir string) (int64, error) {
+    var migrated int64
+    copied := make(map[string]bool)
+
+    for _, mc := range mediaColumns {
+        var urls []string
+        err := db.Unscoped().Model(mc.model).
+            Where(mc.column+" LIKE ?", LegacyUploadPrefix+"%").
+            Distinct().Pluck(mc.column, &urls).Error
+        if err != nil {
+            return migrated, err
+        }
+
+        for _, url := range urls {
+            name := path.Base(strings.TrimPrefix(url, LegacyUploadPrefix))
+            if storage.ValidateKey(name) != nil {
+                log.Printf("Skipping media with an invalid name: %s", url)
+                continue
+            }
+            if !copied[name] {
+                if err := copyLegacyFile(store, filepath.Join(legacyDir, name), name); err != nil {
+                    if errors.Is(err, os.ErrNotExist) {
+                        log.Printf("Skipping missing media file: %s", url)
+                        continue
+                    }
+                    return migrated, err
+                }
+                copied[name] = true
+            }
+
+            result := db.Unscoped().Model(mc.model).Where(mc.column+" = ?", url).Update(mc.column, storage.MediaURL(name))
+            if result.Error != nil {
+                return migrated, result.Error
+            }
+            migrated += result.RowsAffected
+        }
+    }
+    return migrated, nil
+}
+
+// copyLegacyFile uploads a file from disk to store under key
+func copyLegacyFile(store storage.BlobStore, path, key string) error {
+    f, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    return store.Put(context.Background(), key, f, mime.TypeByExtension(filepath.Ext(key)))
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+    "gorm.io/gorm"
+)
+
+// ModerationStatus tracks whether a recipe, review or reply is visible to the public
+type ModerationStatus string
+
+const (
+    StatusPending  ModerationStatus = "pending"  // Held for a moderator, e.g. after tripping the content filter
+    StatusApproved ModerationStatus = "approved" // Publicly visible
+    StatusRejected ModerationStatus = "rejected" // Hidden by a moderator
+    StatusFlagged  ModerationStatus = "flagged"  // Hidden after enough user reports until a moderator decides
+)
+
+// FlagThreshold is the number of open reports that hides approved content
+const FlagThreshold = 3
+
+// Content types that can be reported and moderated
+const (
+    ContentRecipe   = "recipe"
+    ContentFeedback = "feedback"
+    ContentReply    = "reply"
+)
+
+// IsValidModerationStatus checks if the status is one of the known statuses
+func IsValidModerationStatus(status string) bool {
+    switch ModerationStatus(status) {
+    case StatusPending, StatusApproved, StatusRejected, StatusFlagged:
+        return true
+    default:
+        return false
+    }
+}
+
+// Report is a user's complaint about a recipe, review or reply
+type Report struct {
+    gorm.Model
+    ContentType string `json:"content_type" gorm:"size:20;not null;uniqueIndex:idx_report_content_user"` // ContentRecipe, ContentFeedback or ContentReply
+    ContentID   uint   `json:"content_id" gorm:"not null;uniqueIndex:idx_report_content_user"`
+    UserID      uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_report_content_user"` // One report per user per item
+    Reason      string `json:"reason" gorm:"not null"` // spam, offensive, inappropriate or other
+    Details     string `json:"details" gorm:"type:text"`
+    Resolved    bool   `json:"resolved" gorm:"not null;default:false;index"`
+
+    // Relationships
+    User        User   `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// Approved limits a query on table (recipes, feedbacks or feedback_replies) to publicly visible rows
+func Approved(table string) func(*gorm.DB) *gorm.DB {
+    return func(db *gorm.DB) *gorm.DB {
+        return db.Where(table+".status = ?", StatusApproved)
+    }
+}
+
+// RatingContribution returns what a review adds to its recipe's rating
+// aggregates; only approved reviews count
+func RatingContribution(f Feedback) (sum, count int) {
+    if f.Status != StatusApproved || f.DeletedAt.Valid {
+        return 0, 0
+    }
+    return f.Rating, 1
+}
+
+// SetFeedbackStatus moves a review to a new moderation status, keeping its
+// recipe's rating aggregates in step. Call it inside a transaction.
+func SetFeedbackStatus(tx *gorm.DB, feedback *Feedback, status ModerationStatus, note string) error {
+    oldSum, oldCount := RatingContribution(*feedback)
+    if err := tx.Model(feedback).Updates(map[string]interface{}{"status": status, "moderation_note": note}).Error; err != nil {
+        return err
+    }
+    feedback.Status = status
+    feedback.ModerationNote = note
+    newSum, newCount := RatingContribution(*feedback)
+    if newSum == oldSum && newCount == oldCount {
+        return nil
+    }
+    return AdjustRatingAggregates(tx, feedback.RecipeID, newSum-oldSum, newCount-oldCount)
+}
+
+// ResolveReports closes the open reports on an item once a moderator has decided
+func ResolveReports(tx *gorm.DB, contentType string, contentID uint) error {
+    return tx.Model(&Report{}).
+        Where("content_type = ? AND content_id = ? AND resolved = ?", contentType, contentID, false).
+        Updates(map[string]interface{}{"resolved": true}).Error
+}
+
+// OpenReports loads the unresolved reports for the given items, grouped by item ID
+func OpenReports(db *gorm.DB, contentType string, ids []uint) (map[uint][]Report, error) {
+    grouped := make(map[uint][]Report)
+    if len(ids) == 0 {
+        return grouped, nil
+    }
+
+    var reports []Report
+    err := db.Preload("User").
+        Where("content_type = ? AND content_id IN ? AND resolved = ?", contentType, ids, false).
+        Order("id").Find(&reports).Error
+    if err != nil {
+        return nil, err
+    }
+    for _, r := range reports {
+        grouped[r.ContentID] = append(grouped[r.ContentID], r)
+    }
+    return grouped, nil
+}
@@ -4,14 +4,16 @@ import (
     "gorm.io/gorm"
 )
 
-// RecipeCategory defines the available recipe categories
+// RecipeCategory is the key of the Category a recipe belongs to
 type RecipeCategory string
 
+// Keys of the default categories created by SeedCategories
 const (
     PlantBasedMeals RecipeCategory = "plant_based_meals"
     KidsMeals       RecipeCategory = "kids_meals"
     LightMeals      RecipeCategory = "light_meals"
     HeartyMeals     RecipeCategory = "hearty_meals"
+    Stews           RecipeCategory = "stews"
     MeatStews       RecipeCategory = "meat_stews"
     VeggieStews     RecipeCategory = "veggie_stews"
     SeafoodStews    RecipeCategory = "seafood_stews"
@@ -25,58 +27,34 @@ const (
 type Recipe struct {
     gorm.Model
     Title           string         `json:"title" gorm:"not null"`
+    Slug            *string        `json:"slug,omitempty" gorm:"size:120;uniqueIndex"` // Stable key of recipes loaded from fixtures
     Description     string         `json:"description"`
     Ingredients     string         `json:"ingredients" gorm:"type:text;not null"`
     Instructions    string         `json:"instructions" gorm:"type:text;not null"`
     Category        RecipeCategory `json:"category" gorm:"not null"`
+    Cuisine         string         `json:"cuisine,omitempty" gorm:"index"` // Key of a Cuisine; empty when unspecified
+    Locale          string         `json:"locale" gorm:"not null;default:en"` // Language the recipe was written in
+    ContentLocale   string         `json:"content_locale,omitempty" gorm:"-"` // Language of the text in this response; set by LocalizeRecipes
     PrepTime        int            `json:"prep_time"` // in minutes
     CookTime        int            `json:"cook_time"` // in minutes
     Servings        int            `json:"servings"`
     Difficulty      string         `json:"difficulty"` // Easy, Medium, Hard
     ImageURL        string         `json:"image_url"`
+    CardURL         string         `json:"card_url"`      // Cropped variant for recipe cards; empty for default images
+    ThumbnailURL    string         `json:"thumbnail_url"`
     UserID          uint           `json:"user_id" gorm:"not null"` // Foreign key to User
     User            User           `json:"user" gorm:"foreignKey:UserID"`
     Feedbacks       []Feedback     `json:"feedbacks" gorm:"foreignKey:RecipeID"`
-    AverageRating   float64        `json:"average_rating" gorm:"-"` // Calculated field
+    Translations    []RecipeTranslation `json:"translations,omitempty" gorm:"foreignKey:RecipeID"`
+    RatingSum       int            `json:"rating_sum" gorm:"not null;default:0"`       // Sum of all feedback ratings
+    RatingCount     int            `json:"rating_count" gorm:"not null;default:0;index"` // Number of feedback ratings
+    AverageRating   float64        `json:"average_rating" gorm:"not null;default:0;index"` // RatingSum / RatingCount
+    RankingScore    float64        `json:"ranking_score,omitempty" gorm:"-"` // Calculated when ranking recipes
+    TrendingDay     float64        `json:"trending_day" gorm:"not null;default:0;index"`   // Place in the trending order, materialized by ranking.RefreshTrending
+    TrendingWeek    float64        `json:"trending_week" gorm:"not null;default:0;index"`
+    TrendingMonth   float64        `json:"trending_month" gorm:"not null;default:0;index"`
+    Status          ModerationStatus `json:"status" gorm:"not null;default:approved;index"` // Only approved recipes are listed publicly
+    ModerationNote  string         `json:"moderation_note,omitempty"` // Why the recipe was held or rejected
+    ReportCount     int            `json:"report_count" gorm:"not null;default:0"`       // Open user reports
     APIRecipeID     *int           `json:"api_recipe_id" gorm:"default:null"` // stores the recipe ID from Spoonacular API
 }
-
-// GetCategoryDisplayName returns a human-readable category name
-func (r RecipeCategory) GetDisplayName() string {
-    switch r {
-    case PlantBasedMeals:
-        return "Plant-Based Meals"
-    case KidsMeals:
-        return "Kids' Meals"
-    case LightMeals:
-        return "Light Meals (Weight Loss)"
-    case HeartyMeals:
-        return "Hearty Meals (Weight Gain)"
-    case MeatStews:
-        return "Meat Stews"
-    case VeggieStews:
-        return "Veggie Stews"
-    case SeafoodStews:
-        return "Seafood & Fish Stews"
-    case FusionStews:
-        return "Fusion Stews"
-    case Soups:
-        return "Soups"
-    case Drinks:
-        return "Drinks"
-    case Pastries:
-        return "Pastries"
-    default:
-        return string(r)
-    }
-}
-
-// IsValidCategory checks if the category is valid
-func IsValidCategory(category string) bool {
-    switch RecipeCategory(category) {
-    case PlantBasedMeals, KidsMeals, LightMeals, HeartyMeals, MeatStews, VeggieStews, SeafoodStews, FusionStews, Soups, Drinks, Pastries:
-        return true
-    default:
-        return false
-    }
-}
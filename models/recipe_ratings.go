@@ -0,0 +1,37 @@
+package models
+
+import (
+    "gorm.io/gorm"
+)
+
+// AdjustRatingAggregates applies a change in rating sum and count to a recipe's
+// denormalized aggregates. Call it inside the same transaction that writes the feedback.
+func AdjustRatingAggregates(tx *gorm.DB, recipeID uint, sumDelta, countDelta int) error {
+    return tx.Model(&Recipe{}).Where("id = ?", recipeID).Updates(map[string]interface{}{
+        "rating_sum":   gorm.Expr("rating_sum + ?", sumDelta),
+        "rating_count": gorm.Expr("rating_count + ?", countDelta),
+        // Every expression in an UPDATE sees the old row, so recompute from the deltas
+        "average_rating": gorm.Expr(
+            "CASE WHEN rating_count + ? > 0 THEN (rating_sum + ?) * 1.0 / (rating_count + ?) ELSE 0 END",
+            countDelta, sumDelta, countDelta,
+        ),
+    }).Error
+}
+
+// RecomputeRatingAggregates rebuilds every recipe's rating aggregates from its
+// approved feedback and returns how many recipes had drifted out of sync
+func RecomputeRatingAggregates(db *gorm.DB) (int64, error) {
+    const sumSQL = "(SELECT COALESCE(SUM(feedbacks.rating), 0) FROM feedbacks WHERE feedbacks.recipe_id = recipes.id AND feedbacks.deleted_at IS NULL AND feedbacks.status = 'approved')"
+    const countSQL = "(SELECT COUNT(*) FROM feedbacks WHERE feedbacks.recipe_id = recipes.id AND feedbacks.deleted_at IS NULL AND feedbacks.status = 'approved')"
+
+    result := db.Model(&Recipe{}).
+        Where("rating_sum <> " + sumSQL + " OR rating_count <> " + countSQL).
+        Updates(map[string]interface{}{
+            "rating_sum":   gorm.Expr(sumSQL),
+            "rating_count": gorm.Expr(countSQL),
+            "average_rating": gorm.Expr(
+                "CASE WHEN " + countSQL + " > 0 THEN " + sumSQL + " * 1.0 / " + countSQL + " ELSE 0 END",
+            ),
+        })
+    return result.RowsAffected, result.Error
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+    "shei-deli/i18n"
+    "gorm.io/gorm"
+)
+
+// RecipeRecord is the portable form of a recipe read and written by
+// `shei-deli recipe import` and `export`. The author is referenced by username
+// so records can move between databases.
+type RecipeRecord struct {
+    Title        string              `json:"title"`
+    Description  string              `json:"description,omitempty"`
+    Ingredients  string              `json:"ingredients"`
+    Instructions string              `json:"instructions"`
+    Category     string              `json:"category"`
+    Cuisine      string              `json:"cuisine,omitempty"`
+    Locale       string              `json:"locale,omitempty"` // Defaults to English
+    PrepTime     int                 `json:"prep_time"`
+    CookTime     int                 `json:"cook_time"`
+    Servings     int                 `json:"servings"`
+    Difficulty   string              `json:"difficulty,omitempty"`
+    ImageURL     string              `json:"image_url,omitempty"` // Defaults to the category image
+    Author       string              `json:"author,omitempty"`    // Username; defaults to the importer's choice
+    Translations []TranslationRecord `json:"translations,omitempty"`
+}
+
+// TranslationRecord is the portable form of a RecipeTranslation
+type TranslationRecord struct {
+    Locale       string `json:"locale"`
+    Title        string `json:"title"`
+    Description  string `json:"description,omitempty"`
+    Ingredients  string `json:"ingredients,omitempty"`
+    Instructions string `json:"instructions,omitempty"`
+}
+
+// ImportResult counts what ImportRecipes did
+type ImportResult struct {
+    Created int
+    Skipped int // Already present: same title by the same author
+}
+
+// ExportRecipes returns the approved recipes selected by query as records, oldest first
+func ExportRecipes(query *gorm.DB) ([]RecipeRecord, error) {
+    var recipes []Recipe
+    if err := query.Preload("User").Preload("Translations").Scopes(Approved("recipes")).Order("recipes.id").Find(&recipes).Error; err != nil {
+        return nil, err
+    }
+
+    records := make([]RecipeRecord, len(recipes))
+    for i, recipe := range recipes {
+        records[i] = RecipeRecord{
+            Title:        recipe.Title,
+            Description:  recipe.Description,
+            Ingredients:  recipe.Ingredients,
+            Instructions: recipe.Instructions,
+            Category:     string(recipe.Category),
+            Cuisine:      recipe.Cuisine,
+            Locale:       recipe.Locale,
+            PrepTime:     recipe.PrepTime,
+            CookTime:     recipe.CookTime,
+            Servings:     recipe.Servings,
+            Difficulty:   recipe.Difficulty,
+            ImageURL:     recipe.ImageURL,
+            Author:       recipe.User.Username,
+        }
+        for _, translation := range recipe.Translations {
+            records[i].Translations = append(records[i].Translations, TranslationRecord{
+                Locale:       translation.Locale,
+                Title:        translation.Title,
+                Description:  translation.Description,
+                Ingredients:  translation.Ingredients,
+                Instructions: translation.Instructions,
+            })
+        }
+    }
+    return records, nil
+}
+
+// ImportRecipes creates the recipes in records as approved, attributing
+// records without an author to defaultAuthor. Recipes whose author already has
+// one with the same title are skipped, so importing a file twice is harmless.
+// Nothing is imported if any record is invalid.
+func ImportRecipes(db *gorm.DB, records []RecipeRecord, defaultAuthor string) (ImportResult, error) {
+    var result ImportResult
+    err := db.Transaction(func(tx *gorm.DB) error {
+        authors := make(map[string]User)
+        for i, record := range records {
+            created, err := importRecipe(tx, record, defaultAuthor, authors)
+            if err != nil {
+                return fmt.Errorf("recipe %d (%q): %w", i+1, record.Title, err)
+            }
+            if created {
+                result.Created++
+            } else {
+                result.Skipped++
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return ImportResult{}, err
+    }
+    return result, nil
+}
+
+// importRecipe creates one record, caching authors by username
+func importRecipe(tx *gorm.DB, record RecipeRecord, defaultAuthor string, authors map[string]User) (bool, error) {
+    username := record.Author
+    if username == "" {
+        username = defaultAuthor
+    }
+    author, ok := authors[username]
+    if !ok {
+        if err := tx.Where("username = ?", username).First(&author).Error; err != nil {
+            return false, fmt.Errorf("unknown author %q", username)
+        }
+        authors[username] = author
+    }
+
+    recipe, err := NewRecipe(tx, record, author.ID)
+    if err != nil {
+        return false, err
+    }
+
+    var existing int64
+    if err := tx.Model(&Recipe{}).Where("title = ? AND user_id = ?", record.Title, author.ID).Count(&existing).Error; err != nil {
+        return false, err
+    }
+    if existing > 0 {
+        return false, nil
+    }
+
+    // Creating the recipe creates its translations too
+    if err := tx.Create(&recipe).Error; err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
+// NewRecipe validates record and returns the approved recipe, with its
+// translations, that it describes. Nothing is saved.
+func NewRecipe(tx *gorm.DB, record RecipeRecord, authorID uint) (Recipe, error) {
+    if record.Title == "" || record.Ingredients == "" || record.Instructions == "" {
+        return Recipe{}, errors.New("title, ingredients and instructions are required")
+    }
+    var category Category
+    if record.Category == "" || tx.Where(&Category{Key: record.Category}).First(&category).Error != nil {
+        return Recipe{}, fmt.Errorf("unknown category %q", record.Category)
+    }
+    if record.Cuisine != "" && !IsValidCuisine(tx, record.Cuisine) {
+        return Recipe{}, fmt.Errorf("unknown cuisine %q", record.Cuisine)
+    }
+    locale := i18n.DefaultLocale
+    if record.Locale != "" {
+        matched, ok := i18n.Match(record.Locale)
+        if !ok {
+            return Recipe{}, fmt.Errorf("unsupported locale %q", record.Locale)
+        }
+        locale = matched
+    }
+
+    imageURL := record.ImageURL
+    if imageURL == "" {
+        imageURL = category.ImageURL()
+    }
+    recipe := Recipe{
+        Title:        record.Title,
+        Description:  record.Description,
+        Ingredients:  record.Ingredients,
+        Instructions: record.Instructions,
+        Category:     RecipeCategory(record.Category),
+        Cuisine:      record.Cuisine,
+        Locale:       locale,
+        PrepTime:     record.PrepTime,
+        CookTime:     record.CookTime,
+        Servings:     record.Servings,
+        Difficulty:   record.Difficulty,
+        ImageURL:     imageURL,
+        UserID:       authorID,
+        Status:       StatusApproved,
+    }
+
+    for _, translated := range record.Translations {
+        translationLocale, ok := i18n.Match(translated.Locale)
+        if !ok || translationLocale == locale {
+            return Recipe{}, fmt.Errorf("invalid translation locale %q", translated.Locale)
+        }
+        if translated.Title == "" {
+            return Recipe{}, fmt.Errorf("translation %q needs a title", translated.Locale)
+        }
+        recipe.Translations = append(recipe.Translations, RecipeTranslation{
+            Locale:       translationLocale,
+            Title:        translated.Title,
+            Description:  translated.Description,
+            Ingredients:  translated.Ingredients,
+            Instructions: translated.Instructions,
+        })
+    }
+    return recipe, nil
+}
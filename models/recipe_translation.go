@@ -0,0 +1,90 @@
+package models
+
+import (
+    "shei-deli/i18n"
+    "gorm.io/gorm"
+)
+
+// RecipeTranslation holds a recipe's text in a language other than the one it
+// was written in. Empty fields fall back to the original text.
+type RecipeTranslation struct {
+    gorm.Model
+    RecipeID     uint   `json:"recipe_id" gorm:"not null;uniqueIndex:idx_recipe_translation"`
+    Locale       string `json:"locale" gorm:"size:16;not null;uniqueIndex:idx_recipe_translation"`
+    Title        string `json:"title" gorm:"not null"`
+    Description  string `json:"description"`
+    Ingredients  string `json:"ingredients" gorm:"type:text"`
+    Instructions string `json:"instructions" gorm:"type:text"`
+}
+
+// ApplyTranslation replaces the recipe's text with the translated fields that
+// are filled in and records the translation's locale as the content locale
+func (r *Recipe) ApplyTranslation(translation RecipeTranslation) {
+    fields := []struct {
+        dst   *string
+        value string
+    }{
+        {&r.Title, translation.Title},
+        {&r.Description, translation.Description},
+        {&r.Ingredients, translation.Ingredients},
+        {&r.Instructions, translation.Instructions},
+    }
+    for _, f := range fields {
+        if f.value != "" {
+            *f.dst = f.value
+        }
+    }
+    r.ContentLocale = translation.Locale
+}
+
+// LocalizeRecipes shows each recipe in locale, falling back like the message
+// catalog to the base language and then the default locale. The first locale
+// in that chain the recipe was written in or translated into wins; recipes
+// with neither keep their original text.
+func LocalizeRecipes(db *gorm.DB, recipes []Recipe, locale string) error {
+    candidates := i18n.Fallbacks(locale)
+    var ids []uint
+    for i := range recipes {
+        recipes[i].ContentLocale = recipes[i].Locale
+        if recipes[i].Locale != candidates[0] {
+            ids = append(ids, recipes[i].ID)
+        }
+    }
+    if len(ids) == 0 {
+        return nil
+    }
+
+    var translations []RecipeTranslation
+    if err := db.Where("locale IN ? AND recipe_id IN ?", candidates, ids).Find(&translations).Error; err != nil {
+        return err
+    }
+    byRecipe := make(map[uint]map[string]RecipeTranslation, len(translations))
+    for _, translation := range translations {
+        if byRecipe[translation.RecipeID] == nil {
+            byRecipe[translation.RecipeID] = map[string]RecipeTranslation{}
+        }
+        byRecipe[translation.RecipeID][translation.Locale] = translation
+    }
+    for i := range recipes {
+        for _, candidate := range candidates {
+            if candidate == recipes[i].Locale {
+                break
+            }
+            if translation, ok := byRecipe[recipes[i].ID][candidate]; ok {
+                recipes[i].ApplyTranslation(translation)
+                break
+            }
+        }
+    }
+    return nil
+}
+
+// LocalizeRecipe shows a single recipe in locale when it was translated
+func LocalizeRecipe(db *gorm.DB, recipe *Recipe, locale string) error {
+    recipes := []Recipe{*recipe}
+    if err := LocalizeRecipes(db, recipes, locale); err != nil {
+        return err
+    }
+    *recipe = recipes[0]
+    return nil
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+    "time"
+    "gorm.io/gorm"
+)
+
+// TrashRetention is how long deleted recipes and reviews can be restored
+// before the purge job removes them for good
+var TrashRetention = 30 * 24 * time.Hour
+
+// TrashTime returns the deleted_at timestamp to stamp on a group of rows
+// deleted together. It is truncated to what every database can store so the
+// group can be found again by comparing timestamps.
+func TrashTime(now time.Time) time.Time {
+    return now.UTC().Truncate(time.Microsecond)
+}
+
+// RestorableUntil returns when a row deleted at deletedAt will be purged
+func RestorableUntil(deletedAt gorm.DeletedAt) time.Time {
+    return deletedAt.Time.Add(TrashRetention)
+}
+
+// SoftDeleteRecipe moves a recipe to the trash along with its live reviews and
+// their replies and photos. The reviews are hidden with the recipe rather than
+// taken out of its rating, so restoring the recipe restores its rating as well.
+func SoftDeleteRecipe(tx *gorm.DB, recipe *Recipe, at time.Time) error {
+    var feedbackIDs []uint
+    if err := tx.Model(&Feedback{}).Where("recipe_id = ?", recipe.ID).Pluck("id", &feedbackIDs).Error; err != nil {
+        return err
+    }
+    if err := softDeleteFeedbackRows(tx, feedbackIDs, at); err != nil {
+        return err
+    }
+    if err := tx.Model(recipe).Update("deleted_at", at).Error; err != nil {
+        return err
+    }
+    recipe.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+    return nil
+}
+
+// RestoreRecipe brings a trashed recipe back together with the reviews that
+// were deleted along with it; reviews deleted earlier stay in the trash
+func RestoreRecipe(tx *gorm.DB, recipe *Recipe) error {
+    at := recipe.DeletedAt.Time
+    var feedbackIDs []uint
+    err := tx.Unscoped().Model(&Feedback{}).
+        Where("recipe_id = ? AND deleted_at >= ?", recipe.ID, at).
+        Pluck("id", &feedbackIDs).Error
+    if err != nil {
+        return err
+    }
+    if err := restoreFeedbackRows(tx, feedbackIDs, at); err != nil {
+        return err
+    }
+    if err := tx.Unscoped().Model(recipe).Update("deleted_at", nil).Error; err != nil {
+        return err
+    }
+    recipe.DeletedAt = gorm.DeletedAt{}
+    return nil
+}
+
+// SoftDeleteFeedback moves a review and its replies and photos to the trash and
+// takes it out of its recipe's rating
+func SoftDeleteFeedback(tx *gorm.DB, feedback *Feedback, at time.Time) error {
+    sum, count := RatingContribution(*feedback)
+    if err := softDeleteFeedbackRows(tx, []uint{feedback.ID}, at); err != nil {
+        return err
+    }
+    feedback.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+    return AdjustRatingAggregates(tx, feedback.RecipeID, -sum, -count)
+}
+
+// RestoreFeedback brings a trashed review back with the replies and photos
+// deleted along with it
+func RestoreFeedback(tx *gorm.DB, feedback *Feedback) error {
+    if err := restoreFeedbackRows(tx, []uint{feedback.ID}, feedback.DeletedAt.Time); err != nil {
+        return err
+    }
+    feedback.DeletedAt = gorm.DeletedAt{}
+    sum, count := RatingContribution(*feedback)
+    return AdjustRatingAggregates(tx, feedback.RecipeID, sum, count)
+}
+
+// softDeleteFeedbackRows stamps the reviews and their live replies and photos
+// with one deleted_at
+func softDeleteFeedbackRows(tx *gorm.DB, ids []uint, at time.Time) error {
+    if len(ids) == 0 {
+        return nil
+    }
+    for _, model := range []interface{}{&FeedbackReply{}, &FeedbackPhoto{}} {
+        if err := tx.Model(model).Where("feedback_id IN ?", ids).Update("deleted_at", at).Error; err != nil {
+            return err
+        }
+    }
+    return tx.Model(&Feedback{}).Where("id IN ?", ids).Update("deleted_at", at).Error
+}
+
+// restoreFeedbackRows undeletes the reviews and the replies and photos that
+// were deleted with them at or after at
+func restoreFeedbackRows(tx *gorm.DB, ids []uint, at time.Time) error {
+    if len(ids) == 0 {
+        return nil
+    }
+    for _, model := range []interface{}{&FeedbackReply{}, &FeedbackPhoto{}} {
+        err := tx.Unscoped().Model(model).
+            Where("feedback_id IN ? AND deleted_at >= ?", ids, at).
+            Update("deleted_at", nil).Error
+        if err != nil {
+            return err
+        }
+    }
+    return tx.Unscoped().Model(&Feedback{}).Where("id IN ?", ids).Update("deleted_at", nil).Error
+}
+
+// TrashOrphanedFeedback moves live reviews of already-deleted recipes into the
+// trash with their recipe, for databases from before deletes cascaded
+func TrashOrphanedFeedback(db *gorm.DB) (int64, error) {
+    result := db.Exec(`UPDATE feedbacks SET deleted_at = (
+        SELECT recipes.deleted_at FROM recipes WHERE recipes.id = feedbacks.recipe_id)
+        WHERE deleted_at IS NULL AND recipe_id IN (SELECT id FROM recipes WHERE deleted_at IS NOT NULL)`)
+    return result.RowsAffected, result.Error
+}
+
+// PurgeResult reports what PurgeExpired removed
+type PurgeResult struct {
+    Recipes   int64
+    Feedbacks int64
+    MediaURLs []string // Uploaded images that belonged to purged rows
+}
+
+// PurgeExpired hard-deletes recipes and reviews deleted before the cutoff,
+// together with everything that hangs off them. The caller removes MediaURLs
+// from the media store once the transaction has committed.
+func PurgeExpired(db *gorm.DB, before time.Time) (PurgeResult, error) {
+    var result PurgeResult
+    before = TrashTime(before)
+    err := db.Transaction(func(tx *gorm.DB) error {
+        var recipeIDs []uint
+        if err := tx.Unscoped().Model(&Recipe{}).Where("deleted_at < ?", before).Pluck("id", &recipeIDs).Error; err != nil {
+            return err
+        }
+
+        var feedbackIDs []uint
+        err := tx.Unscoped().Model(&Feedback{}).
+            Where("deleted_at < ? OR recipe_id IN ?", before, recipeIDs).
+            Pluck("id", &feedbackIDs).Error
+        if err != nil {
+            return err
+        }
+
+        result, err = purgeRows(tx, recipeIDs, feedbackIDs)
+        return err
+    })
+    return result, err
+}
+
+// purgeRows hard-deletes the given recipes and reviews with their photos,
+// replies, votes, reports and translations, without touching rating aggregates
+func purgeRows(tx *gorm.DB, recipeIDs, feedbackIDs []uint) (PurgeResult, error) {
+    var result PurgeResult
+    if err := tx.Unscoped().Model(&Recipe{}).Where("id IN ?", recipeIDs).Pluck("image_url", &result.MediaURLs).Error; err != nil {
+        return result, err
+    }
+
+    var photoURLs []string
+    if err := tx.Unscoped().Model(&FeedbackPhoto{}).Where("feedback_id IN ?", feedbackIDs).Pluck("url", &photoURLs).Error; err != nil {
+        return result, err
+    }
+    result.MediaURLs = append(result.MediaURLs, photoURLs...)
+
+    var replyIDs []uint
+    if err := tx.Unscoped().Model(&FeedbackReply{}).Where("feedback_id IN ?", feedbackIDs).Pluck("id", &replyIDs).Error; err != nil {
+        return result, err
+    }
+    for _, model := range []interface{}{&FeedbackPhoto{}, &FeedbackReply{}, &FeedbackVote{}} {
+        if err := tx.Unscoped().Where("feedback_id IN ?", feedbackIDs).Delete(model).Error; err != nil {
+            return result, err
+        }
+    }
+    err := tx.Unscoped().
+        Where("(content_type = ? AND content_id IN ?) OR (content_type = ? AND content_id IN ?) OR (content_type = ? AND content_id IN ?)",
+            ContentRecipe, recipeIDs, ContentFeedback, feedbackIDs, ContentReply, replyIDs).
+        Delete(&Report{}).Error
+    if err != nil {
+        return result, err
+    }
+
+    if err := tx.Unscoped().Where("recipe_id IN ?", recipeIDs).Delete(&RecipeTranslation{}).Error; err != nil {
+        return result, err
+    }
+
+    deleted := tx.Unscoped().Where("id IN ?", feedbackIDs).Delete(&Feedback{})
+    if deleted.Error != nil {
+        return result, deleted.Error
+    }
+    result.Feedbacks = deleted.RowsAffected
+
+    deleted = tx.Unscoped().Where("id IN ?", recipeIDs).Delete(&Recipe{})
+    if deleted.Error != nil {
+        return result, deleted.Error
+    }
+    result.Recipes = deleted.RowsAffected
+    return result, nil
+}
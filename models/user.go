@@ -2,27 +2,43 @@ package models
 
 import (
     "time"
+    "unicode"
     "gorm.io/gorm"
 )
 
 // User model stores user data for community features
 type User struct {
     gorm.Model
-    Username    string    `json:"username" gorm:"uniqueIndex;not null"`
-    Email       string    `json:"email" gorm:"uniqueIndex;not null"`
+    Username    string    `json:"username" gorm:"size:64;uniqueIndex;not null"`
+    Email       string    `json:"email" gorm:"size:254;uniqueIndex;not null"`
     Password    string    `json:"-" gorm:"not null"` // Hidden from JSON responses
     FirstName   string    `json:"first_name"`
     LastName    string    `json:"last_name"`
     Bio         string    `json:"bio"`
     AvatarURL   string    `json:"avatar_url"`
     IsActive    bool      `json:"is_active" gorm:"default:true"`
+    Role        string    `json:"role" gorm:"not null;default:user"` // RoleUser, RoleModerator or RoleAdmin
+    Locale      string    `json:"locale"` // Preferred language; empty uses the browser's
     JoinedAt    time.Time `json:"joined_at" gorm:"autoCreateTime"`
+    AnonymizedAt *time.Time `json:"anonymized_at,omitempty"` // Set when the account was deleted and its personal data scrubbed
     
     // Relationships
     Recipes     []Recipe   `json:"recipes" gorm:"foreignKey:UserID"`
     Feedbacks   []Feedback `json:"feedbacks" gorm:"foreignKey:UserID"`
 }
 
+// User roles; moderators and admins can review the moderation queue
+const (
+    RoleUser      = "user"
+    RoleModerator = "moderator"
+    RoleAdmin     = "admin"
+)
+
+// CanModerate reports whether the user may review reported and held content
+func (u User) CanModerate() bool {
+    return u.IsActive && (u.Role == RoleModerator || u.Role == RoleAdmin)
+}
+
 // GetFullName returns the user's full name
 func (u *User) GetFullName() string {
     if u.FirstName != "" && u.LastName != "" {
@@ -31,10 +47,36 @@ func (u *User) GetFullName() string {
     return u.Username
 }
 
+// IsAnonymized reports whether the account was deleted and scrubbed
+func (u User) IsAnonymized() bool {
+    return u.AnonymizedAt != nil
+}
+
 // GetDisplayName returns the best available display name
 func (u User) GetDisplayName() string {
+    if u.IsAnonymized() {
+        return "Former member"
+    }
     if u.FirstName != "" {
         return u.FirstName
     }
     return u.Username
 }
+
+// StrongPassword reports whether password is 8 to 72 characters long with at
+// least one letter and one digit, the rule for every account password
+func StrongPassword(password string) bool {
+    if len(password) < 8 || len(password) > 72 {
+        return false
+    }
+    var hasLetter, hasDigit bool
+    for _, r := range password {
+        switch {
+        case unicode.IsLetter(r):
+            hasLetter = true
+        case unicode.IsDigit(r):
+            hasDigit = true
+        }
+    }
+    return hasLetter && hasDigit
+}
@@ -0,0 +1,131 @@
+package moderation
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+    "unicode"
+    "shei-deli/models"
+)
+
+// Filter screens submitted text for profanity and spam. Content that trips it
+// is held as pending for a moderator instead of being rejected outright, so
+// false positives cost a delay rather than a lost recipe.
+type Filter struct {
+    BannedWords      []string `json:"banned_words"`       // Matched as whole words, ignoring case and simple letter swaps (sh1t)
+    SpamPhrases      []string `json:"spam_phrases"`       // Matched anywhere, ignoring case and spacing
+    MaxLinks         int      `json:"max_links"`          // More links than this looks like spam; negative disables the check
+    MaxRepeatedChars int      `json:"max_repeated_chars"` // Longest allowed run of one character; 0 disables the check
+    RequireApproval  bool     `json:"require_approval"`   // Hold every new submission for review
+}
+
+// DefaultFilter returns a filter with a small built-in word and phrase list
+func DefaultFilter() *Filter {
+    return &Filter{
+        BannedWords: []string{"fuck", "fucking", "shit", "bitch", "cunt", "asshole", "bastard", "dickhead", "wanker", "motherfucker"},
+        SpamPhrases: []string{
+            "buy now", "click here", "free money", "work from home", "make money fast",
+            "casino", "viagra", "crypto giveaway", "limited time offer", "dm me for",
+        },
+        MaxLinks:         2,
+        MaxRepeatedChars: 10,
+    }
+}
+
+// LoadFilter reads a JSON filter definition; fields left out keep their defaults
+func LoadFilter(path string) (*Filter, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    filter := DefaultFilter()
+    if err := json.Unmarshal(data, filter); err != nil {
+        return nil, fmt.Errorf("parse moderation filter %s: %w", path, err)
+    }
+    return filter, nil
+}
+
+// Check returns the reasons the texts trip the filter, or nil if they are clean
+func (f *Filter) Check(texts ...string) []string {
+    text := strings.ToLower(strings.Join(texts, "\n"))
+    var reasons []string
+
+    words := make(map[string]bool)
+    for _, word := range strings.FieldsFunc(normalizeLeet(text), isWordSeparator) {
+        words[word] = true
+    }
+    for _, banned := range f.BannedWords {
+        if words[strings.ToLower(banned)] {
+            reasons = append(reasons, "profanity")
+            break
+        }
+    }
+
+    collapsed := strings.Join(strings.Fields(text), " ")
+    for _, phrase := range f.SpamPhrases {
+        if phrase != "" && strings.Contains(collapsed, strings.ToLower(phrase)) {
+            reasons = append(reasons, "spam phrase")
+            break
+        }
+    }
+
+    if f.MaxLinks >= 0 {
+        links := strings.Count(text, "http://") + strings.Count(text, "https://") + strings.Count(text, "www.")
+        if links > f.MaxLinks {
+            reasons = append(reasons, "too many links")
+        }
+    }
+
+    if f.MaxRepeatedChars > 0 && longestRun(text) > f.MaxRepeatedChars {
+        reasons = append(reasons, "repeated characters")
+    }
+    return reasons
+}
+
+// Status decides the moderation status and note of a submission. prev and
+// prevNote are the status and note of the content being edited, or empty for
+// new content: an approved item stays approved if it is still clean, while one
+// already hidden stays hidden and keeps the moderator's note.
+func (f *Filter) Status(prev models.ModerationStatus, prevNote string, texts ...string) (models.ModerationStatus, string) {
+    if reasons := f.Check(texts...); len(reasons) > 0 {
+        return models.StatusPending, "Held by content filter: " + strings.Join(reasons, ", ")
+    }
+    switch prev {
+    case models.StatusPending, models.StatusFlagged:
+        return prev, prevNote
+    case models.StatusRejected:
+        return models.StatusPending, "Edited after rejection"
+    }
+    if prev == "" && f.RequireApproval {
+        return models.StatusPending, "Awaiting moderator approval"
+    }
+    return models.StatusApproved, ""
+}
+
+// normalizeLeet undoes common letter substitutions used to dodge word lists
+func normalizeLeet(s string) string {
+    return strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "@", "a", "$", "s").Replace(s)
+}
+
+func isWordSeparator(r rune) bool {
+    return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// longestRun returns the length of the longest run of one repeated non-space character
+func longestRun(s string) int {
+    longest, run := 0, 0
+    var last rune
+    for _, r := range s {
+        if r == last && !unicode.IsSpace(r) {
+            run++
+        } else {
+            run = 1
+        }
+        last = r
+        if run > longest {
+            longest = run
+        }
+    }
+    return longest
+}
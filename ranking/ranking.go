@@ -0,0 +1,131 @@
+package ranking
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "time"
+    "shei-deli/models"
+)
+
+// Method selects the scoring formula used to rank recipes
+type Method string
+
+const (
+    // Bayesian shrinks each recipe's average towards the site-wide mean in
+    // proportion to how few ratings it has
+    Bayesian Method = "bayesian"
+    // Wilson ranks by the lower bound of the Wilson score interval for the
+    // share of the rating scale a recipe earned
+    Wilson Method = "wilson"
+    // TimeDecay is the Bayesian score halved every HalfLifeDays since the
+    // recipe was published, favouring fresh content
+    TimeDecay Method = "time_decay"
+)
+
+// Params are the tunable scoring parameters; they are echoed back in API
+// responses so clients can see exactly how a ranking was produced
+type Params struct {
+    Method       Method  `json:"method"`
+    PriorMean    float64 `json:"prior_mean"`     // Bayesian: rating assumed before any reviews
+    PriorWeight  float64 `json:"prior_weight"`   // Bayesian: how many reviews the prior is worth
+    ConfidenceZ  float64 `json:"confidence_z"`   // Wilson: z-score of the confidence level
+    HalfLifeDays float64 `json:"half_life_days"` // TimeDecay: days for a score to halve
+}
+
+// DefaultParams returns the default parameters for a method
+func DefaultParams(method Method) Params {
+    return Params{
+        Method:       method,
+        PriorMean:    3.0,
+        PriorWeight:  5,
+        ConfidenceZ:  1.96, // 95% confidence
+        HalfLifeDays: 30,
+    }
+}
+
+// Validate checks that the parameters are usable
+func (p Params) Validate() error {
+    switch p.Method {
+    case Bayesian, Wilson, TimeDecay:
+    default:
+        return fmt.Errorf("unknown ranking method %q", p.Method)
+    }
+    if p.PriorMean < 1 || p.PriorMean > 5 {
+        return fmt.Errorf("prior_mean must be between 1 and 5")
+    }
+    if p.PriorWeight < 0 {
+        return fmt.Errorf("prior_weight must not be negative")
+    }
+    if p.ConfidenceZ <= 0 {
+        return fmt.Errorf("confidence_z must be positive")
+    }
+    if p.HalfLifeDays <= 0 {
+        return fmt.Errorf("half_life_days must be positive")
+    }
+    return nil
+}
+
+// Score computes a recipe's ranking score; higher is better
+func (p Params) Score(ratingSum, ratingCount int, createdAt, now time.Time) float64 {
+    switch p.Method {
+    case Wilson:
+        return WilsonLowerBound(ratingSum, ratingCount, p.ConfidenceZ)
+    case TimeDecay:
+        ageDays := now.Sub(createdAt).Hours() / 24
+        return Decay(BayesianAverage(ratingSum, ratingCount, p.PriorMean, p.PriorWeight), ageDays, p.HalfLifeDays)
+    default:
+        return BayesianAverage(ratingSum, ratingCount, p.PriorMean, p.PriorWeight)
+    }
+}
+
+// BayesianAverage returns (C*m + sum) / (C + n) for prior mean m and weight C
+func BayesianAverage(ratingSum, ratingCount int, priorMean, priorWeight float64) float64 {
+    denominator := priorWeight + float64(ratingCount)
+    if denominator == 0 {
+        return priorMean
+    }
+    return (priorWeight*priorMean + float64(ratingSum)) / denominator
+}
+
+// WilsonLowerBound maps a 1-5 star average onto a 0-1 proportion, takes the
+// lower bound of its Wilson score interval, and maps it back onto 1-5 stars
+func WilsonLowerBound(ratingSum, ratingCount int, z float64) float64 {
+    if ratingCount == 0 {
+        return 1
+    }
+    n := float64(ratingCount)
+    phat := (float64(ratingSum)/n - 1) / 4
+    z2 := z * z
+    lower := (phat + z2/(2*n) - z*math.Sqrt((phat*(1-phat)+z2/(4*n))/n)) / (1 + z2/n)
+    return 1 + 4*math.Max(lower, 0)
+}
+
+// Decay halves score every halfLifeDays of age
+func Decay(score, ageDays, halfLifeDays float64) float64 {
+    if ageDays <= 0 {
+        return score
+    }
+    return score * math.Pow(0.5, ageDays/halfLifeDays)
+}
+
+// Rank scores recipes in place and sorts them best first, breaking ties by
+// rating count, then newest, then ID so the order is stable
+func Rank(recipes []models.Recipe, p Params, now time.Time) {
+    for i := range recipes {
+        recipes[i].RankingScore = p.Score(recipes[i].RatingSum, recipes[i].RatingCount, recipes[i].CreatedAt, now)
+    }
+    sort.SliceStable(recipes, func(i, j int) bool {
+        a, b := recipes[i], recipes[j]
+        if a.RankingScore != b.RankingScore {
+            return a.RankingScore > b.RankingScore
+        }
+        if a.RatingCount != b.RatingCount {
+            return a.RatingCount > b.RatingCount
+        }
+        if !a.CreatedAt.Equal(b.CreatedAt) {
+            return a.CreatedAt.After(b.CreatedAt)
+        }
+        return a.ID < b.ID
+    })
+}
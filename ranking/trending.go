@@ -0,0 +1,177 @@
+package ranking
+
+import (
+    "fmt"
+    "log"
+    "math"
+    "sort"
+    "time"
+    "shei-deli/jobs"
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// Window is a trending time window
+type Window string
+
+const (
+    Day   Window = "day"
+    Week  Window = "week"
+    Month Window = "month"
+)
+
+// Windows lists the supported trending windows, shortest first
+var Windows = []Window{Day, Week, Month}
+
+// Duration returns the length of the window
+func (w Window) Duration() time.Duration {
+    switch w {
+    case Day:
+        return 24 * time.Hour
+    case Month:
+        return 30 * 24 * time.Hour
+    default:
+        return 7 * 24 * time.Hour
+    }
+}
+
+// Column returns the recipes column holding the window's materialized score
+func (w Window) Column() string {
+    return "trending_" + string(w)
+}
+
+// place returns the recipe's materialized place in the window's order
+func (w Window) place(r models.Recipe) float64 {
+    switch w {
+    case Day:
+        return r.TrendingDay
+    case Month:
+        return r.TrendingMonth
+    default:
+        return r.TrendingWeek
+    }
+}
+
+// ParseWindow validates a window name
+func ParseWindow(s string) (Window, error) {
+    for _, w := range Windows {
+        if string(w) == s {
+            return w, nil
+        }
+    }
+    return "", fmt.Errorf("window must be one of: day, week, month")
+}
+
+// Heat is the contribution of one rating of the given age to a window's
+// trending score: its distance from the prior, halved every half window and
+// zero outside it, so ratings below the prior cool a recipe down
+func Heat(rating int, prior float64, age, window time.Duration) float64 {
+    if age < 0 {
+        age = 0
+    }
+    if age > window {
+        return 0
+    }
+    halfLife := window / 2
+    return (float64(rating) - prior) * math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// TrendingParams are the parameters trending ties are ranked with: the
+// time-decayed Bayesian score, with the site average as the prior once
+// anything has been rated
+func TrendingParams(siteAverage float64, rated bool) Params {
+    params := DefaultParams(TimeDecay)
+    if rated {
+        params.PriorMean = siteAverage
+    }
+    return params
+}
+
+// trendingRow is a recent rating used to compute trending scores
+type trendingRow struct {
+    RecipeID  uint
+    Rating    int
+    CreatedAt time.Time
+}
+
+// RefreshTrending recomputes the materialized trending order for every
+// window. Recipes are ordered by the heat of the ratings received within the
+// window, with Rank breaking ties, and each column stores the recipe's place
+// in that order counted from the bottom so the hottest recipe has the highest
+// value.
+func RefreshTrending(db *gorm.DB, now time.Time) error {
+    longest := Windows[len(Windows)-1].Duration()
+
+    var recipes []models.Recipe
+    if err := db.Select("id, status, rating_sum, rating_count, created_at, trending_day, trending_week, trending_month").
+        Find(&recipes).Error; err != nil {
+        return err
+    }
+    var rows []trendingRow
+    if err := db.Model(&models.Feedback{}).
+        Select("recipe_id, rating, created_at").
+        Where("created_at >= ? AND status = ?", now.Add(-longest), models.StatusApproved).
+        Scan(&rows).Error; err != nil {
+        return err
+    }
+
+    // Like the Bayesian prior, only public recipes count towards the site average
+    ratingSum, ratingCount := 0, 0
+    for _, r := range recipes {
+        if r.Status != models.StatusApproved {
+            continue
+        }
+        ratingSum += r.RatingSum
+        ratingCount += r.RatingCount
+    }
+    params := TrendingParams(float64(ratingSum)/float64(max(ratingCount, 1)), ratingCount > 0)
+
+    heat := make(map[Window]map[uint]float64, len(Windows))
+    for _, w := range Windows {
+        heat[w] = make(map[uint]float64)
+    }
+    for _, row := range rows {
+        for _, w := range Windows {
+            heat[w][row.RecipeID] += Heat(row.Rating, params.PriorMean, now.Sub(row.CreatedAt), w.Duration())
+        }
+    }
+
+    // Sorting the Rank order stably by heat leaves Rank deciding between equals
+    Rank(recipes, params, now)
+    updates := make(map[uint]map[string]interface{})
+    for _, w := range Windows {
+        order := append([]models.Recipe(nil), recipes...)
+        sort.SliceStable(order, func(i, j int) bool {
+            return heat[w][order[i].ID] > heat[w][order[j].ID]
+        })
+        for i, r := range order {
+            place := float64(len(order) - i)
+            if w.place(r) == place {
+                continue
+            }
+            if updates[r.ID] == nil {
+                updates[r.ID] = map[string]interface{}{}
+            }
+            updates[r.ID][w.Column()] = place
+        }
+    }
+
+    return db.Transaction(func(tx *gorm.DB) error {
+        for recipeID, columns := range updates {
+            if err := tx.Model(&models.Recipe{}).Where("id = ?", recipeID).Updates(columns).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// StartTrendingRefresher refreshes trending scores immediately and then every
+// interval in the background. Call the returned function to stop it.
+func StartTrendingRefresher(db *gorm.DB, interval time.Duration) func() {
+    return jobs.Every(interval, func() {
+        if err := RefreshTrending(db, time.Now()); err != nil {
+            log.Printf("Failed to refresh trending scores: %v", err)
+        }
+    })
+}
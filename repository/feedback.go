@@ -0,0 +1,361 @@
+package repository
+
+import (
+    "time"
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// FeedbackRepository loads and stores reviews with their photos, replies and
+// votes, keeping the reviewed recipes' rating aggregates in step
+type FeedbackRepository interface {
+    // ForRecipe returns one page of a recipe's approved reviews with their
+    // authors, photos and reply threads
+    ForRecipe(recipeID uint, page PageRequest, sort SortOption) ([]models.Feedback, PageInfo, error)
+    // Reviews returns all of a recipe's approved reviews, most helpful first,
+    // with their authors, photos and reply threads
+    Reviews(recipeID uint) ([]models.Feedback, error)
+    // Queue returns one page of reviews in any of statuses, oldest first
+    Queue(statuses []models.ModerationStatus, page PageRequest) ([]models.Feedback, PageInfo, error)
+    // Find loads a review whatever its moderation status
+    Find(id uint) (models.Feedback, error)
+    // FindApproved loads an approved review
+    FindApproved(id uint) (models.Feedback, error)
+    // FindByAuthor loads a user's review of a recipe, even one in the trash
+    FindByAuthor(recipeID, userID uint) (models.Feedback, error)
+    // Create saves a new review with its photos and counts it towards the
+    // recipe's rating. The review is reloaded with its author, recipe and photos.
+    Create(feedback *models.Feedback, photos []models.FeedbackPhoto) error
+    // Replace overwrites existing, the same user's earlier review of the
+    // recipe, with feedback, bringing it back from the trash with its photos
+    // and adding the new ones. The review is reloaded like after Create.
+    Replace(existing models.Feedback, feedback *models.Feedback, photos []models.FeedbackPhoto) error
+    // Update applies the non-empty fields of changes, always writing its
+    // moderation note, and moves the recipe's rating by the difference. The
+    // review is reloaded with its author and recipe.
+    Update(feedback *models.Feedback, changes models.Feedback) error
+    // Delete moves a review and its photos to the trash
+    Delete(feedback *models.Feedback, at time.Time) error
+    // Trash returns a user's reviews deleted since since, newest deletion
+    // first, with their recipes. Reviews deleted along with their recipe
+    // come back with it, so only reviews of live recipes are listed.
+    Trash(userID uint, since time.Time) ([]models.Feedback, error)
+    // FindTrashed loads a deleted review
+    FindTrashed(id uint) (models.Feedback, error)
+    // Restore brings a deleted review back from the trash. The review is
+    // reloaded like after Create.
+    Restore(feedback *models.Feedback) error
+    // Moderate applies a moderator's decision, keeping the recipe's rating in
+    // step, and resolves the review's reports. The review is reloaded with
+    // its author and recipe.
+    Moderate(feedback *models.Feedback, status models.ModerationStatus, note string) error
+    // Count returns the number of approved reviews
+    Count() (int64, error)
+    // FindReply loads an approved reply in a review's thread
+    FindReply(feedbackID, replyID uint) (models.FeedbackReply, error)
+    // AddReply saves a reply and loads its author
+    AddReply(reply *models.FeedbackReply) error
+    // ReplyQueue returns one page of replies in any of statuses, oldest
+    // first, with their authors and the reviews and recipes they answer
+    ReplyQueue(statuses []models.ModerationStatus, page PageRequest) ([]models.FeedbackReply, PageInfo, error)
+    // Reply loads a reply whatever its moderation status
+    Reply(id uint) (models.FeedbackReply, error)
+    // ModerateReply applies a moderator's decision and resolves the reply's
+    // reports. The reply is reloaded with its author.
+    ModerateReply(reply *models.FeedbackReply, status models.ModerationStatus, note string) error
+    // Vote records or changes a user's helpfulness vote and reloads the counts
+    Vote(feedback *models.Feedback, userID uint, helpful bool) error
+    // RetractVote removes a user's vote and reloads the counts
+    RetractVote(feedback *models.Feedback, userID uint) error
+    // Photos returns one page of the photos on a recipe's approved reviews, newest first
+    Photos(recipeID uint, page PageRequest) ([]models.FeedbackPhoto, PageInfo, error)
+    // LatestPhotos returns up to limit of a recipe's newest photos
+    LatestPhotos(recipeID uint, limit int) ([]models.FeedbackPhoto, error)
+}
+
+type feedbackRepository struct {
+    db *gorm.DB
+}
+
+// NewFeedbackRepository returns a FeedbackRepository backed by db
+func NewFeedbackRepository(db *gorm.DB) FeedbackRepository {
+    return &feedbackRepository{db: db}
+}
+
+func feedbackKey(f models.Feedback) uint    { return f.ID }
+func replyKey(r models.FeedbackReply) uint  { return r.ID }
+func photoKey(p models.FeedbackPhoto) uint  { return p.ID }
+
+// approvedPhotos limits a feedback_photos query to photos on approved reviews
+func approvedPhotos(db *gorm.DB) *gorm.DB {
+    approved := db.Session(&gorm.Session{NewDB: true}).Model(&models.Feedback{}).Select("id").Scopes(models.Approved("feedbacks"))
+    return db.Where("feedback_photos.feedback_id IN (?)", approved)
+}
+
+func (r *feedbackRepository) ForRecipe(recipeID uint, page PageRequest, sort SortOption) ([]models.Feedback, PageInfo, error) {
+    query := r.db.Preload("User").Preload("Photos").Scopes(models.Approved("feedbacks")).Where("recipe_id = ?", recipeID)
+    feedbacks, info, err := paginate(query, page, "feedbacks", sort, feedbackKey)
+    if err != nil {
+        return nil, info, err
+    }
+    return feedbacks, info, models.LoadReplyThreads(r.db, feedbacks)
+}
+
+func (r *feedbackRepository) Reviews(recipeID uint) ([]models.Feedback, error) {
+    var feedbacks []models.Feedback
+    err := r.db.Preload("User").Preload("Photos").Scopes(models.Approved("feedbacks")).
+        Where("recipe_id = ?", recipeID).
+        Order("(helpful_count - unhelpful_count) DESC, id DESC").
+        Find(&feedbacks).Error
+    if err != nil {
+        return nil, err
+    }
+    return feedbacks, models.LoadReplyThreads(r.db, feedbacks)
+}
+
+func (r *feedbackRepository) Queue(statuses []models.ModerationStatus, page PageRequest) ([]models.Feedback, PageInfo, error) {
+    query := r.db.Preload("User").Preload("Recipe").Where("feedbacks.status IN ?", statuses)
+    return paginate(query, page, "feedbacks", SortOption{}, feedbackKey)
+}
+
+func (r *feedbackRepository) Find(id uint) (models.Feedback, error) {
+    var feedback models.Feedback
+    err := r.db.First(&feedback, id).Error
+    return feedback, err
+}
+
+func (r *feedbackRepository) FindApproved(id uint) (models.Feedback, error) {
+    var feedback models.Feedback
+    err := r.db.Scopes(models.Approved("feedbacks")).First(&feedback, id).Error
+    return feedback, err
+}
+
+func (r *feedbackRepository) FindByAuthor(recipeID, userID uint) (models.Feedback, error) {
+    var feedback models.Feedback
+    err := r.db.Unscoped().Where("recipe_id = ? AND user_id = ?", recipeID, userID).First(&feedback).Error
+    return feedback, err
+}
+
+func (r *feedbackRepository) Create(feedback *models.Feedback, photos []models.FeedbackPhoto) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(feedback).Error; err != nil {
+            return err
+        }
+        if err := attachPhotos(tx, *feedback, photos); err != nil {
+            return err
+        }
+        sum, count := models.RatingContribution(*feedback)
+        return models.AdjustRatingAggregates(tx, feedback.RecipeID, sum, count)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").Preload("Recipe").Preload("Photos").First(feedback, feedback.ID)
+    return nil
+}
+
+func (r *feedbackRepository) Replace(existing models.Feedback, feedback *models.Feedback, photos []models.FeedbackPhoto) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        oldSum, oldCount := models.RatingContribution(existing)
+        err := tx.Unscoped().Model(&existing).Updates(map[string]interface{}{
+            "comment":         feedback.Comment,
+            "rating":          feedback.Rating,
+            "status":          feedback.Status,
+            "moderation_note": feedback.ModerationNote,
+            "deleted_at":      nil,
+        }).Error
+        if err != nil {
+            return err
+        }
+        feedback.ID = existing.ID
+        if existing.DeletedAt.Valid {
+            // Photos were removed with the review; bring them back with it
+            if err := tx.Unscoped().Model(&models.FeedbackPhoto{}).Where("feedback_id = ?", existing.ID).Update("deleted_at", nil).Error; err != nil {
+                return err
+            }
+        }
+        if err := attachPhotos(tx, *feedback, photos); err != nil {
+            return err
+        }
+        newSum, newCount := models.RatingContribution(*feedback)
+        return models.AdjustRatingAggregates(tx, feedback.RecipeID, newSum-oldSum, newCount-oldCount)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").Preload("Recipe").Preload("Photos").First(feedback, feedback.ID)
+    return nil
+}
+
+// attachPhotos records uploaded photos against a review
+func attachPhotos(tx *gorm.DB, feedback models.Feedback, photos []models.FeedbackPhoto) error {
+    for _, photo := range photos {
+        photo.FeedbackID = feedback.ID
+        photo.RecipeID = feedback.RecipeID
+        photo.UserID = feedback.UserID
+        if err := tx.Create(&photo).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (r *feedbackRepository) Update(feedback *models.Feedback, changes models.Feedback) error {
+    oldSum, oldCount := models.RatingContribution(*feedback)
+    updated := *feedback
+    updated.Status = changes.Status
+    if changes.Rating != 0 {
+        updated.Rating = changes.Rating
+    }
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(feedback).Updates(changes).Error; err != nil {
+            return err
+        }
+        // Updates skips empty fields, and a clean edit clears the old note
+        if err := tx.Model(feedback).Update("moderation_note", changes.ModerationNote).Error; err != nil {
+            return err
+        }
+        newSum, newCount := models.RatingContribution(updated)
+        if newSum == oldSum && newCount == oldCount {
+            return nil
+        }
+        return models.AdjustRatingAggregates(tx, feedback.RecipeID, newSum-oldSum, newCount-oldCount)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").Preload("Recipe").First(feedback, feedback.ID)
+    return nil
+}
+
+func (r *feedbackRepository) Delete(feedback *models.Feedback, at time.Time) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        return models.SoftDeleteFeedback(tx, feedback, at)
+    })
+}
+
+func (r *feedbackRepository) Trash(userID uint, since time.Time) ([]models.Feedback, error) {
+    var feedbacks []models.Feedback
+    err := r.db.Unscoped().Preload("Recipe").
+        Where("feedbacks.user_id = ? AND feedbacks.deleted_at IS NOT NULL AND feedbacks.deleted_at >= ?", userID, models.TrashTime(since)).
+        Where("feedbacks.recipe_id IN (?)", r.db.Model(&models.Recipe{}).Select("id")).
+        Order("feedbacks.deleted_at DESC").Find(&feedbacks).Error
+    return feedbacks, err
+}
+
+func (r *feedbackRepository) FindTrashed(id uint) (models.Feedback, error) {
+    var feedback models.Feedback
+    err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&feedback, id).Error
+    return feedback, err
+}
+
+func (r *feedbackRepository) Restore(feedback *models.Feedback) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        return models.RestoreFeedback(tx, feedback)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").Preload("Recipe").Preload("Photos").First(feedback, feedback.ID)
+    return nil
+}
+
+func (r *feedbackRepository) Moderate(feedback *models.Feedback, status models.ModerationStatus, note string) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := models.SetFeedbackStatus(tx, feedback, status, note); err != nil {
+            return err
+        }
+        if err := tx.Model(feedback).Update("report_count", 0).Error; err != nil {
+            return err
+        }
+        return models.ResolveReports(tx, models.ContentFeedback, feedback.ID)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").Preload("Recipe").First(feedback, feedback.ID)
+    return nil
+}
+
+func (r *feedbackRepository) Count() (int64, error) {
+    var count int64
+    err := r.db.Model(&models.Feedback{}).Scopes(models.Approved("feedbacks")).Count(&count).Error
+    return count, err
+}
+
+func (r *feedbackRepository) FindReply(feedbackID, replyID uint) (models.FeedbackReply, error) {
+    var reply models.FeedbackReply
+    err := r.db.Scopes(models.Approved("feedback_replies")).Where("feedback_id = ?", feedbackID).First(&reply, replyID).Error
+    return reply, err
+}
+
+func (r *feedbackRepository) AddReply(reply *models.FeedbackReply) error {
+    if err := r.db.Create(reply).Error; err != nil {
+        return err
+    }
+    r.db.Preload("User").First(reply, reply.ID)
+    return nil
+}
+
+func (r *feedbackRepository) ReplyQueue(statuses []models.ModerationStatus, page PageRequest) ([]models.FeedbackReply, PageInfo, error) {
+    query := r.db.Preload("User").Preload("Feedback.Recipe").Where("feedback_replies.status IN ?", statuses)
+    return paginate(query, page, "feedback_replies", SortOption{}, replyKey)
+}
+
+func (r *feedbackRepository) Reply(id uint) (models.FeedbackReply, error) {
+    var reply models.FeedbackReply
+    err := r.db.First(&reply, id).Error
+    return reply, err
+}
+
+func (r *feedbackRepository) ModerateReply(reply *models.FeedbackReply, status models.ModerationStatus, note string) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        err := tx.Model(reply).Updates(map[string]interface{}{
+            "status":          status,
+            "moderation_note": note,
+            "report_count":    0,
+        }).Error
+        if err != nil {
+            return err
+        }
+        return models.ResolveReports(tx, models.ContentReply, reply.ID)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").First(reply, reply.ID)
+    return nil
+}
+
+func (r *feedbackRepository) Vote(feedback *models.Feedback, userID uint, helpful bool) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        return models.CastVote(tx, feedback.ID, userID, helpful)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.First(feedback, feedback.ID)
+    return nil
+}
+
+func (r *feedbackRepository) RetractVote(feedback *models.Feedback, userID uint) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        return models.RetractVote(tx, feedback.ID, userID)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.First(feedback, feedback.ID)
+    return nil
+}
+
+func (r *feedbackRepository) Photos(recipeID uint, page PageRequest) ([]models.FeedbackPhoto, PageInfo, error) {
+    query := r.db.Preload("User").Scopes(approvedPhotos).Where("recipe_id = ?", recipeID)
+    return paginate(query, page, "feedback_photos", SortOption{Desc: true}, photoKey)
+}
+
+func (r *feedbackRepository) LatestPhotos(recipeID uint, limit int) ([]models.FeedbackPhoto, error) {
+    var photos []models.FeedbackPhoto
+    err := r.db.Preload("User").Scopes(approvedPhotos).Where("recipe_id = ?", recipeID).Order("id DESC").Limit(limit).Find(&photos).Error
+    return photos, err
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+    "gorm.io/gorm"
+)
+
+// ErrCursorSort is returned when a cursor was handed out for a different sort
+var ErrCursorSort = errors.New("cursor does not match the requested sort")
+
+// PageRequest describes which slice of a list the client asked for. Offset
+// mode uses Page and Limit; cursor mode (CursorMode, with a nil Cursor for the
+// first page) pages by the opaque cursors handed out in PageInfo.
+type PageRequest struct {
+    Limit      int
+    Page       int
+    CursorMode bool
+    Cursor     *Cursor
+}
+
+// PageInfo is the pagination metadata returned alongside every list. The
+// repositories fill in everything but the Next and Prev links, which depend
+// on the request URL.
+type PageInfo struct {
+    Mode       string `json:"mode"`
+    Limit      int    `json:"limit"`
+    Total      int64  `json:"total"`
+    Page       int    `json:"page,omitempty"`
+    TotalPages int    `json:"total_pages,omitempty"`
+    NextCursor string `json:"next_cursor,omitempty"`
+    PrevCursor string `json:"prev_cursor,omitempty"`
+    Next       string `json:"next,omitempty"`
+    Prev       string `json:"prev,omitempty"`
+}
+
+// Cursor marks a position in a sorted list: the sort value of the boundary
+// row plus its ID as a tie-breaker
+type Cursor struct {
+    Sort     string      `json:"s,omitempty"`
+    Value    interface{} `json:"v,omitempty"`
+    IsTime   bool        `json:"t,omitempty"`
+    ID       uint        `json:"id"`
+    Backward bool        `json:"b,omitempty"`
+}
+
+// Encode returns the cursor in the opaque form handed to clients
+func (pc Cursor) Encode() string {
+    if t, ok := pc.Value.(time.Time); ok {
+        pc.Value = t.Format(time.RFC3339Nano)
+        pc.IsTime = true
+    }
+    b, _ := json.Marshal(pc)
+    return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+    b, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    var pc Cursor
+    if err := json.Unmarshal(b, &pc); err != nil {
+        return nil, err
+    }
+    if pc.IsTime {
+        str, _ := pc.Value.(string)
+        t, err := time.Parse(time.RFC3339Nano, str)
+        if err != nil {
+            return nil, err
+        }
+        pc.Value = t
+    }
+    return &pc, nil
+}
+
+// SortOption describes how a list is ordered. Expr is the primary SQL sort
+// expression (empty to sort by ID alone); the row ID always breaks ties in the
+// same direction so the order is stable and usable as a cursor key. Expr
+// must not be NULL for any row, since NULL never satisfies the cursor
+// predicate; nullable expressions set NullValue, the SQL value that stands in
+// for NULL both when ordering and when paging.
+type SortOption struct {
+    Key       string
+    Expr      string
+    NullValue string
+    Desc      bool
+}
+
+// sortExpr returns Expr with NULLs replaced by NullValue
+func (so SortOption) sortExpr() string {
+    if so.NullValue == "" {
+        return so.Expr
+    }
+    return fmt.Sprintf("COALESCE(%s, %s)", so.Expr, so.NullValue)
+}
+
+// OrderBy returns the ORDER BY clause for the option, optionally reversed
+func (so SortOption) OrderBy(table string, reverse bool) string {
+    dir := "ASC"
+    if so.Desc != reverse {
+        dir = "DESC"
+    }
+    if so.Expr == "" {
+        return fmt.Sprintf("%s.id %s", table, dir)
+    }
+    return fmt.Sprintf("%s %s, %s.id %s", so.sortExpr(), dir, table, dir)
+}
+
+// paginate loads one page of query into a slice ordered by sort and returns
+// the pagination metadata. idOf extracts an item's ID so cursors can be built
+// from the first and last rows of the page.
+func paginate[T any](query *gorm.DB, req PageRequest, table string, sort SortOption, idOf func(T) uint) ([]T, PageInfo, error) {
+    var items []T
+    info := PageInfo{Limit: req.Limit}
+
+    if err := query.Session(&gorm.Session{}).Model(new(T)).Count(&info.Total).Error; err != nil {
+        return nil, info, err
+    }
+
+    if !req.CursorMode {
+        info.Mode = "offset"
+        info.Page = req.Page
+        info.TotalPages = int((info.Total + int64(req.Limit) - 1) / int64(req.Limit))
+
+        err := query.Order(sort.OrderBy(table, false)).
+            Offset((req.Page - 1) * req.Limit).
+            Limit(req.Limit).
+            Find(&items).Error
+        if err != nil {
+            return nil, info, err
+        }
+        return items, info, nil
+    }
+
+    info.Mode = "cursor"
+    if req.Cursor != nil && req.Cursor.Sort != sort.Key {
+        return nil, info, ErrCursorSort
+    }
+    backward := req.Cursor != nil && req.Cursor.Backward
+
+    // Walking backwards flips both the comparison and the sort order; the
+    // page is reversed afterwards so items always come out in list order
+    q := query.Order(sort.OrderBy(table, backward)).Limit(req.Limit + 1)
+    if req.Cursor != nil {
+        op := ">"
+        if sort.Desc != backward {
+            op = "<"
+        }
+        if sort.Expr == "" {
+            q = q.Where(fmt.Sprintf("%s.id %s ?", table, op), req.Cursor.ID)
+        } else {
+            q = q.Where(fmt.Sprintf("(%[1]s %[2]s ?) OR (%[1]s = ? AND %[3]s.id %[2]s ?)", sort.sortExpr(), op, table),
+                req.Cursor.Value, req.Cursor.Value, req.Cursor.ID)
+        }
+    }
+    if err := q.Find(&items).Error; err != nil {
+        return nil, info, err
+    }
+
+    hasMore := len(items) > req.Limit
+    if hasMore {
+        items = items[:req.Limit]
+    }
+    if backward {
+        for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+            items[i], items[j] = items[j], items[i]
+        }
+    }
+
+    if len(items) > 0 {
+        hasNext := hasMore || backward
+        hasPrev := req.Cursor != nil && (!backward || hasMore)
+        if hasNext {
+            cursor, err := newCursor(query, table, sort, idOf(items[len(items)-1]), false)
+            if err != nil {
+                return nil, info, err
+            }
+            info.NextCursor = cursor
+        }
+        if hasPrev {
+            cursor, err := newCursor(query, table, sort, idOf(items[0]), true)
+            if err != nil {
+                return nil, info, err
+            }
+            info.PrevCursor = cursor
+        }
+    }
+    return items, info, nil
+}
+
+// newCursor builds a cursor for the row with the given ID, looking up its sort value
+func newCursor(query *gorm.DB, table string, sort SortOption, id uint, backward bool) (string, error) {
+    cursor := Cursor{Sort: sort.Key, ID: id, Backward: backward}
+    if sort.Expr != "" {
+        var value interface{}
+        row := query.Session(&gorm.Session{NewDB: true}).Table(table).Select(sort.sortExpr()).Where(table+".id = ?", id).Row()
+        if err := row.Scan(&value); err != nil {
+            return "", err
+        }
+        if b, ok := value.([]byte); ok {
+            value = string(b)
+        }
+        cursor.Value = value
+    }
+    return cursor.Encode(), nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "time"
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// RecipeRepository loads and stores recipes
+type RecipeRepository interface {
+    // List returns one page of approved recipes matching filter, with their authors
+    List(filter RecipeFilter, page PageRequest, sort SortOption) ([]models.Recipe, PageInfo, error)
+    // All returns every approved recipe matching filter, with their authors
+    All(filter RecipeFilter, sort SortOption) ([]models.Recipe, error)
+    // Queue returns one page of recipes in any of statuses, oldest first
+    Queue(statuses []models.ModerationStatus, page PageRequest) ([]models.Recipe, PageInfo, error)
+    // Find loads a recipe whatever its moderation status
+    Find(id uint) (models.Recipe, error)
+    // FindApproved loads an approved recipe with its author
+    FindApproved(id uint) (models.Recipe, error)
+    // FindWithReviews loads an approved recipe with its author and approved reviews
+    FindWithReviews(id uint) (models.Recipe, error)
+    // Create saves a new recipe and loads its author
+    Create(recipe *models.Recipe) error
+    // Update applies the non-empty fields of changes, always writing its
+    // moderation note so a clean edit clears an old one. clearVariants drops
+    // the card and thumbnail of a replaced image. The recipe is reloaded.
+    Update(recipe *models.Recipe, changes models.Recipe, clearVariants bool) error
+    // Delete moves a recipe and its reviews to the trash
+    Delete(recipe *models.Recipe, at time.Time) error
+    // Trash returns a user's recipes deleted since since, newest deletion first
+    Trash(userID uint, since time.Time) ([]models.Recipe, error)
+    // FindTrashed loads a deleted recipe
+    FindTrashed(id uint) (models.Recipe, error)
+    // Restore brings a deleted recipe and its reviews back from the trash and
+    // loads its author
+    Restore(recipe *models.Recipe) error
+    // Moderate applies a moderator's decision and resolves the recipe's
+    // reports. The recipe is reloaded with its author.
+    Moderate(recipe *models.Recipe, status models.ModerationStatus, note string) error
+    // Localize shows recipes in locale where their authors translated them
+    Localize(recipes []models.Recipe, locale string) error
+    // LocalizeOne shows a recipe in locale if its author translated it
+    LocalizeOne(recipe *models.Recipe, locale string) error
+    // Translations returns a recipe's translations ordered by locale
+    Translations(recipeID uint) ([]models.RecipeTranslation, error)
+    // SaveTranslation creates or replaces the recipe's translation into
+    // translation.Locale, moving the recipe to status with note if its status
+    // changes. created reports whether the translation is new.
+    SaveTranslation(recipe *models.Recipe, translation *models.RecipeTranslation, status models.ModerationStatus, note string) (created bool, err error)
+    // DeleteTranslation removes a recipe's translation into locale for good,
+    // so it can be translated again; ErrNotFound if there is none
+    DeleteTranslation(recipeID uint, locale string) error
+    // Export returns the approved recipes matching filter as records, oldest first
+    Export(filter RecipeFilter) ([]models.RecipeRecord, error)
+    // Import creates approved recipes from records, crediting defaultAuthor
+    // for records that name none; see models.ImportRecipes
+    Import(records []models.RecipeRecord, defaultAuthor string) (models.ImportResult, error)
+    // Count returns the number of approved recipes
+    Count() (int64, error)
+    // AverageRating returns the mean of all ratings on the site; ok is false
+    // while nothing has been rated
+    AverageRating() (avg float64, ok bool, err error)
+}
+
+// RecipeFilter narrows a recipe listing; zero fields match every recipe
+type RecipeFilter struct {
+    Categories []string // Category keys, usually a category and its subcategories
+    Cuisine    string
+    UserID     uint
+    Rated      bool // Only recipes with at least one rating
+}
+
+type recipeRepository struct {
+    db *gorm.DB
+}
+
+// NewRecipeRepository returns a RecipeRepository backed by db
+func NewRecipeRepository(db *gorm.DB) RecipeRepository {
+    return &recipeRepository{db: db}
+}
+
+func recipeKey(r models.Recipe) uint { return r.ID }
+
+// approved starts a query over approved recipes matching filter
+func (r *recipeRepository) approved(filter RecipeFilter) *gorm.DB {
+    query := r.db.Preload("User").Scopes(models.Approved("recipes"))
+    if len(filter.Categories) > 0 {
+        query = query.Where("recipes.category IN ?", filter.Categories)
+    }
+    if filter.Cuisine != "" {
+        query = query.Where("recipes.cuisine = ?", filter.Cuisine)
+    }
+    if filter.UserID != 0 {
+        query = query.Where("recipes.user_id = ?", filter.UserID)
+    }
+    if filter.Rated {
+        query = query.Where("recipes.rating_count > 0")
+    }
+    return query
+}
+
+func (r *recipeRepository) List(filter RecipeFilter, page PageRequest, sort SortOption) ([]models.Recipe, PageInfo, error) {
+    return paginate(r.approved(filter), page, "recipes", sort, recipeKey)
+}
+
+func (r *recipeRepository) All(filter RecipeFilter, sort SortOption) ([]models.Recipe, error) {
+    var recipes []models.Recipe
+    err := r.approved(filter).Order(sort.OrderBy("recipes", false)).Find(&recipes).Error
+    return recipes, err
+}
+
+func (r *recipeRepository) Export(filter RecipeFilter) ([]models.RecipeRecord, error) {
+    return models.ExportRecipes(r.approved(filter))
+}
+
+func (r *recipeRepository) Import(records []models.RecipeRecord, defaultAuthor string) (models.ImportResult, error) {
+    return models.ImportRecipes(r.db, records, defaultAuthor)
+}
+
+func (r *recipeRepository) Queue(statuses []models.ModerationStatus, page PageRequest) ([]models.Recipe, PageInfo, error) {
+    query := r.db.Preload("User").Where("recipes.status IN ?", statuses)
+    return paginate(query, page, "recipes", SortOption{}, recipeKey)
+}
+
+func (r *recipeRepository) Find(id uint) (models.Recipe, error) {
+    var recipe models.Recipe
+    err := r.db.First(&recipe, id).Error
+    return recipe, err
+}
+
+func (r *recipeRepository) FindApproved(id uint) (models.Recipe, error) {
+    var recipe models.Recipe
+    err := r.db.Preload("User").Scopes(models.Approved("recipes")).First(&recipe, id).Error
+    return recipe, err
+}
+
+func (r *recipeRepository) FindWithReviews(id uint) (models.Recipe, error) {
+    var recipe models.Recipe
+    err := r.db.Preload("User").
+        Preload("Feedbacks", models.Approved("feedbacks")).Preload("Feedbacks.User").
+        Scopes(models.Approved("recipes")).First(&recipe, id).Error
+    return recipe, err
+}
+
+func (r *recipeRepository) Create(recipe *models.Recipe) error {
+    if err := r.db.Create(recipe).Error; err != nil {
+        return err
+    }
+    r.db.Preload("User").First(recipe, recipe.ID)
+    return nil
+}
+
+func (r *recipeRepository) Update(recipe *models.Recipe, changes models.Recipe, clearVariants bool) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(recipe).Updates(changes).Error; err != nil {
+            return err
+        }
+        if err := tx.Model(recipe).Update("moderation_note", changes.ModerationNote).Error; err != nil {
+            return err
+        }
+        if !clearVariants {
+            return nil
+        }
+        return tx.Model(recipe).Updates(map[string]interface{}{"card_url": "", "thumbnail_url": ""}).Error
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").First(recipe, recipe.ID)
+    return nil
+}
+
+func (r *recipeRepository) Delete(recipe *models.Recipe, at time.Time) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        return models.SoftDeleteRecipe(tx, recipe, at)
+    })
+}
+
+func (r *recipeRepository) Trash(userID uint, since time.Time) ([]models.Recipe, error) {
+    var recipes []models.Recipe
+    err := r.db.Unscoped().
+        Where("user_id = ? AND deleted_at IS NOT NULL AND deleted_at >= ?", userID, models.TrashTime(since)).
+        Order("deleted_at DESC").Find(&recipes).Error
+    return recipes, err
+}
+
+func (r *recipeRepository) FindTrashed(id uint) (models.Recipe, error) {
+    var recipe models.Recipe
+    err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&recipe, id).Error
+    return recipe, err
+}
+
+func (r *recipeRepository) Restore(recipe *models.Recipe) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        return models.RestoreRecipe(tx, recipe)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").First(recipe, recipe.ID)
+    return nil
+}
+
+func (r *recipeRepository) Moderate(recipe *models.Recipe, status models.ModerationStatus, note string) error {
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        err := tx.Model(recipe).Updates(map[string]interface{}{
+            "status":          status,
+            "moderation_note": note,
+            "report_count":    0,
+        }).Error
+        if err != nil {
+            return err
+        }
+        return models.ResolveReports(tx, models.ContentRecipe, recipe.ID)
+    })
+    if err != nil {
+        return err
+    }
+    r.db.Preload("User").First(recipe, recipe.ID)
+    return nil
+}
+
+func (r *recipeRepository) Localize(recipes []models.Recipe, locale string) error {
+    return models.LocalizeRecipes(r.db, recipes, locale)
+}
+
+func (r *recipeRepository) LocalizeOne(recipe *models.Recipe, locale string) error {
+    return models.LocalizeRecipe(r.db, recipe, locale)
+}
+
+func (r *recipeRepository) Translations(recipeID uint) ([]models.RecipeTranslation, error) {
+    var translations []models.RecipeTranslation
+    err := r.db.Where("recipe_id = ?", recipeID).Order("locale").Find(&translations).Error
+    return translations, err
+}
+
+func (r *recipeRepository) SaveTranslation(recipe *models.Recipe, translation *models.RecipeTranslation, status models.ModerationStatus, note string) (bool, error) {
+    created := false
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var existing models.RecipeTranslation
+        err := tx.Where(&models.RecipeTranslation{RecipeID: recipe.ID, Locale: translation.Locale}).First(&existing).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            created = true
+        } else if err != nil {
+            return err
+        }
+
+        translation.RecipeID = recipe.ID
+        translation.Model = existing.Model
+        if err := tx.Save(translation).Error; err != nil {
+            return err
+        }
+
+        if status == recipe.Status {
+            return nil
+        }
+        return tx.Model(recipe).Updates(map[string]interface{}{"status": status, "moderation_note": note}).Error
+    })
+    return created, err
+}
+
+func (r *recipeRepository) DeleteTranslation(recipeID uint, locale string) error {
+    deleted := r.db.Unscoped().Where("recipe_id = ? AND locale = ?", recipeID, locale).Delete(&models.RecipeTranslation{})
+    if deleted.Error != nil {
+        return deleted.Error
+    }
+    if deleted.RowsAffected == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+func (r *recipeRepository) Count() (int64, error) {
+    var count int64
+    err := r.db.Model(&models.Recipe{}).Scopes(models.Approved("recipes")).Count(&count).Error
+    return count, err
+}
+
+func (r *recipeRepository) AverageRating() (float64, bool, error) {
+    var avg sql.NullFloat64
+    err := r.db.Model(&models.Recipe{}).Scopes(models.Approved("recipes")).
+        Select("SUM(rating_sum) * 1.0 / NULLIF(SUM(rating_count), 0)").
+        Scan(&avg).Error
+    return avg.Float64, avg.Valid, err
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+    "errors"
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// ReportRepository stores users' reports of recipes, reviews and replies
+type ReportRepository interface {
+    // Filed reports whether a user has already reported an item
+    Filed(contentType string, contentID, userID uint) (bool, error)
+    // File saves a report and hides the item once it has
+    // models.FlagThreshold open reports. It returns the item's status.
+    File(report *models.Report) (models.ModerationStatus, error)
+    // Open loads the unresolved reports on the given items, grouped by item ID
+    Open(contentType string, ids []uint) (map[uint][]models.Report, error)
+}
+
+type reportRepository struct {
+    db *gorm.DB
+}
+
+// NewReportRepository returns a ReportRepository backed by db
+func NewReportRepository(db *gorm.DB) ReportRepository {
+    return &reportRepository{db: db}
+}
+
+func (r *reportRepository) Filed(contentType string, contentID, userID uint) (bool, error) {
+    var existing models.Report
+    err := r.db.Where("content_type = ? AND content_id = ? AND user_id = ?", contentType, contentID, userID).First(&existing).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return false, nil
+    }
+    return err == nil, err
+}
+
+func (r *reportRepository) File(report *models.Report) (models.ModerationStatus, error) {
+    status := models.StatusApproved
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(report).Error; err != nil {
+            return err
+        }
+
+        switch report.ContentType {
+        case models.ContentRecipe:
+            var recipe models.Recipe
+            if err := tx.First(&recipe, report.ContentID).Error; err != nil {
+                return err
+            }
+            recipe.ReportCount++
+            updates := map[string]interface{}{"report_count": recipe.ReportCount}
+            if recipe.ReportCount >= models.FlagThreshold {
+                updates["status"] = models.StatusFlagged
+                updates["moderation_note"] = "Hidden after user reports"
+                status = models.StatusFlagged
+            }
+            return tx.Model(&recipe).Updates(updates).Error
+        case models.ContentReply:
+            var reply models.FeedbackReply
+            if err := tx.First(&reply, report.ContentID).Error; err != nil {
+                return err
+            }
+            reply.ReportCount++
+            updates := map[string]interface{}{"report_count": reply.ReportCount}
+            if reply.ReportCount >= models.FlagThreshold {
+                updates["status"] = models.StatusFlagged
+                updates["moderation_note"] = "Hidden after user reports"
+                status = models.StatusFlagged
+            }
+            return tx.Model(&reply).Updates(updates).Error
+        default:
+            var feedback models.Feedback
+            if err := tx.First(&feedback, report.ContentID).Error; err != nil {
+                return err
+            }
+            feedback.ReportCount++
+            if err := tx.Model(&feedback).Update("report_count", feedback.ReportCount).Error; err != nil {
+                return err
+            }
+            if feedback.ReportCount < models.FlagThreshold {
+                return nil
+            }
+            status = models.StatusFlagged
+            return models.SetFeedbackStatus(tx, &feedback, models.StatusFlagged, "Hidden after user reports")
+        }
+    })
+    return status, err
+}
+
+func (r *reportRepository) Open(contentType string, ids []uint) (map[uint][]models.Report, error) {
+    return models.OpenReports(r.db, contentType, ids)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+    "gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no row matches a lookup. It is gorm's own
+// error, so apperrors.FromDB reports it as a 404 whichever side it came from.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Repositories bundles the repositories the handlers are built from
+type Repositories struct {
+    Recipes  RecipeRepository
+    Feedback FeedbackRepository
+    Users    UserRepository
+    Taxonomy TaxonomyRepository
+    Reports  ReportRepository
+}
+
+// New returns repositories backed by db
+func New(db *gorm.DB) Repositories {
+    return Repositories{
+        Recipes:  NewRecipeRepository(db),
+        Feedback: NewFeedbackRepository(db),
+        Users:    NewUserRepository(db),
+        Taxonomy: NewTaxonomyRepository(db),
+        Reports:  NewReportRepository(db),
+    }
+}
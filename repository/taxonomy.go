@@ -0,0 +1,126 @@
+package repository
+
+import (
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// TaxonomyRepository loads and stores the categories and cuisines recipes are filed under
+type TaxonomyRepository interface {
+    // Categories returns all categories in display order
+    Categories() ([]models.Category, error)
+    // FindCategory loads a category by key
+    FindCategory(key string) (models.Category, error)
+    // CategoryKeys returns key followed by the keys of all its subcategories at any depth
+    CategoryKeys(key string) ([]string, error)
+    // Family returns a category and all its subcategories at any depth, in display order
+    Family(key string) ([]models.Category, error)
+    // Subcategories returns the categories directly under key, in display order
+    Subcategories(key string) ([]models.Category, error)
+    // IsCategory reports whether a category with this key exists
+    IsCategory(key string) bool
+    // CategoryName returns a category's display name in locale, or the key if it is unknown
+    CategoryName(key models.RecipeCategory, locale string) string
+    // CategoryImageURL returns the image shown for recipes in a category that
+    // have no image of their own
+    CategoryImageURL(key models.RecipeCategory) string
+    // CategoryInUse reports whether any recipe, trashed ones included, is filed under key
+    CategoryInUse(key string) (bool, error)
+    // CreateCategory saves a new category
+    CreateCategory(category *models.Category) error
+    // UpdateCategory applies updates, keyed by column, to a category
+    UpdateCategory(category *models.Category, updates map[string]interface{}) error
+    // DeleteCategory removes a category for good so its key can be reused
+    DeleteCategory(category *models.Category) error
+    // Cuisines returns all cuisines in display order
+    Cuisines() ([]models.Cuisine, error)
+    // FindCuisine loads a cuisine by key
+    FindCuisine(key string) (models.Cuisine, error)
+    // IsCuisine reports whether a cuisine with this key exists
+    IsCuisine(key string) bool
+    // CuisineName returns a cuisine's display name, or the key if it is unknown
+    CuisineName(key string) string
+}
+
+type taxonomyRepository struct {
+    db *gorm.DB
+}
+
+// NewTaxonomyRepository returns a TaxonomyRepository backed by db
+func NewTaxonomyRepository(db *gorm.DB) TaxonomyRepository {
+    return &taxonomyRepository{db: db}
+}
+
+func (r *taxonomyRepository) Categories() ([]models.Category, error) {
+    return models.ListCategories(r.db)
+}
+
+func (r *taxonomyRepository) FindCategory(key string) (models.Category, error) {
+    return models.FindCategory(r.db, key)
+}
+
+func (r *taxonomyRepository) CategoryKeys(key string) ([]string, error) {
+    return models.CategoryKeysWithDescendants(r.db, key)
+}
+
+func (r *taxonomyRepository) Family(key string) ([]models.Category, error) {
+    keys, err := r.CategoryKeys(key)
+    if err != nil {
+        return nil, err
+    }
+    var family []models.Category
+    err = r.db.Where(map[string]interface{}{"key": keys}).Order("sort_order, id").Find(&family).Error
+    return family, err
+}
+
+func (r *taxonomyRepository) Subcategories(key string) ([]models.Category, error) {
+    var subcategories []models.Category
+    err := r.db.Where("parent_key = ?", key).Order("sort_order, id").Find(&subcategories).Error
+    return subcategories, err
+}
+
+func (r *taxonomyRepository) IsCategory(key string) bool {
+    return models.IsValidCategory(r.db, key)
+}
+
+func (r *taxonomyRepository) CategoryName(key models.RecipeCategory, locale string) string {
+    return models.LocalizedCategoryName(r.db, key, locale)
+}
+
+func (r *taxonomyRepository) CategoryImageURL(key models.RecipeCategory) string {
+    return models.CategoryImageURL(r.db, key)
+}
+
+func (r *taxonomyRepository) CategoryInUse(key string) (bool, error) {
+    var count int64
+    err := r.db.Unscoped().Model(&models.Recipe{}).Where("category = ?", key).Count(&count).Error
+    return count > 0, err
+}
+
+func (r *taxonomyRepository) CreateCategory(category *models.Category) error {
+    return r.db.Create(category).Error
+}
+
+func (r *taxonomyRepository) UpdateCategory(category *models.Category, updates map[string]interface{}) error {
+    return r.db.Model(category).Updates(updates).Error
+}
+
+func (r *taxonomyRepository) DeleteCategory(category *models.Category) error {
+    return r.db.Unscoped().Delete(category).Error
+}
+
+func (r *taxonomyRepository) Cuisines() ([]models.Cuisine, error) {
+    return models.ListCuisines(r.db)
+}
+
+func (r *taxonomyRepository) FindCuisine(key string) (models.Cuisine, error) {
+    return models.FindCuisine(r.db, key)
+}
+
+func (r *taxonomyRepository) IsCuisine(key string) bool {
+    return models.IsValidCuisine(r.db, key)
+}
+
+func (r *taxonomyRepository) CuisineName(key string) string {
+    return models.CuisineName(r.db, key)
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+    "time"
+    "shei-deli/models"
+    "gorm.io/gorm"
+)
+
+// UserRepository loads and stores accounts
+type UserRepository interface {
+    // List returns one page of users without their password hashes
+    List(page PageRequest) ([]models.User, PageInfo, error)
+    // Find loads a user by ID
+    Find(id uint) (models.User, error)
+    // FindByLogin loads the user whose username or email is login
+    FindByLogin(login string) (models.User, error)
+    // FindProfile loads a user with their approved recipes and reviews
+    FindProfile(id uint) (models.User, error)
+    // Taken reports whether an account already uses username or email
+    Taken(username, email string) (bool, error)
+    // Create saves a new account
+    Create(user *models.User) error
+    // UpdateProfile applies the non-empty fields of changes
+    UpdateProfile(user *models.User, changes models.User) error
+    // SetActive deactivates or reactivates an account
+    SetActive(user *models.User, active bool) error
+    // SetRole gives an account a new role
+    SetRole(user *models.User, role string) error
+    // Delete scrubs an account's personal data, first removing its recipes
+    // and reviews when removeContent is set. The result lists the media the
+    // caller should remove from the store.
+    Delete(user *models.User, removeContent bool, at time.Time) (models.PurgeResult, error)
+    // Export gathers everything stored about a user, trashed rows included
+    Export(user models.User) (UserExport, error)
+    // Count returns the number of accounts
+    Count() (int64, error)
+}
+
+// UserExport is everything stored about a user, as written to the export archive
+type UserExport struct {
+    Profile   models.User            `json:"profile"`
+    Recipes   []models.Recipe        `json:"recipes"`
+    Feedbacks []models.Feedback      `json:"feedbacks"`
+    Replies   []models.FeedbackReply `json:"replies"`
+    Votes     []models.FeedbackVote  `json:"votes"`
+    Reports   []models.Report        `json:"reports"`
+}
+
+type userRepository struct {
+    db *gorm.DB
+}
+
+// NewUserRepository returns a UserRepository backed by db
+func NewUserRepository(db *gorm.DB) UserRepository {
+    return &userRepository{db: db}
+}
+
+func userKey(u models.User) uint { return u.ID }
+
+func (r *userRepository) List(page PageRequest) ([]models.User, PageInfo, error) {
+    query := r.db.Select("id, username, email, first_name, last_name, bio, avatar_url, is_active, role, locale, joined_at, anonymized_at")
+    return paginate(query, page, "users", SortOption{}, userKey)
+}
+
+func (r *userRepository) Find(id uint) (models.User, error) {
+    var user models.User
+    err := r.db.First(&user, id).Error
+    return user, err
+}
+
+func (r *userRepository) FindByLogin(login string) (models.User, error) {
+    var user models.User
+    err := r.db.Where("username = ? OR email = ?", login, login).First(&user).Error
+    return user, err
+}
+
+func (r *userRepository) FindProfile(id uint) (models.User, error) {
+    var user models.User
+    err := r.db.Preload("Recipes", models.Approved("recipes")).Preload("Feedbacks", models.Approved("feedbacks")).First(&user, id).Error
+    return user, err
+}
+
+func (r *userRepository) Taken(username, email string) (bool, error) {
+    var count int64
+    err := r.db.Model(&models.User{}).Where("username = ? OR email = ?", username, email).Count(&count).Error
+    return count > 0, err
+}
+
+func (r *userRepository) Create(user *models.User) error {
+    return r.db.Create(user).Error
+}
+
+func (r *userRepository) UpdateProfile(user *models.User, changes models.User) error {
+    return r.db.Model(user).Updates(changes).Error
+}
+
+func (r *userRepository) SetActive(user *models.User, active bool) error {
+    return models.SetUserActive(r.db, user, active)
+}
+
+func (r *userRepository) SetRole(user *models.User, role string) error {
+    return r.db.Model(user).Update("role", role).Error
+}
+
+func (r *userRepository) Delete(user *models.User, removeContent bool, at time.Time) (models.PurgeResult, error) {
+    var removed models.PurgeResult
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if removeContent {
+            var err error
+            if removed, err = models.RemoveUserContent(tx, user.ID); err != nil {
+                return err
+            }
+        }
+        return models.AnonymizeUser(tx, user, at)
+    })
+    if err != nil {
+        return models.PurgeResult{}, err
+    }
+    return removed, nil
+}
+
+func (r *userRepository) Export(user models.User) (UserExport, error) {
+    export := UserExport{Profile: user}
+    withDeleted := func(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+    db := r.db.Unscoped().Session(&gorm.Session{})
+
+    if err := db.Preload("Translations").Where("user_id = ?", user.ID).Order("id").Find(&export.Recipes).Error; err != nil {
+        return export, err
+    }
+    if err := db.Preload("Photos", withDeleted).Where("user_id = ?", user.ID).Order("id").Find(&export.Feedbacks).Error; err != nil {
+        return export, err
+    }
+    if err := db.Where("user_id = ?", user.ID).Order("id").Find(&export.Replies).Error; err != nil {
+        return export, err
+    }
+    if err := db.Where("user_id = ?", user.ID).Order("id").Find(&export.Votes).Error; err != nil {
+        return export, err
+    }
+    if err := db.Where("user_id = ?", user.ID).Order("id").Find(&export.Reports).Error; err != nil {
+        return export, err
+    }
+    return export, nil
+}
+
+func (r *userRepository) Count() (int64, error) {
+    var count int64
+    err := r.db.Model(&models.User{}).Count(&count).Error
+    return count, err
+}
@@ -3,24 +3,57 @@ package routes
 import (
     "github.com/gin-gonic/gin"
     "shei-deli/controllers"
+    "shei-deli/middleware"
 )
 
-// SetupRoutes sets up API routes for the application
-func SetupRoutes() *gin.Engine {
-    router := gin.Default()
+var (
+    staticDir = "./static"
+    imagesDir = "./images"
+)
+
+// ConfigureAssets sets the directories served under /static and /images
+func ConfigureAssets(static, images string) {
+    staticDir, imagesDir = static, images
+}
+
+// SetupRoutes sets up API routes for the application, building every
+// controller from s
+func SetupRoutes(s controllers.Services) *gin.Engine {
+    recipeController := controllers.NewRecipeController(s)
+    feedbackController := controllers.NewFeedbackController(s)
+    translationController := controllers.NewTranslationController(s)
+    moderationController := controllers.NewModerationController(s)
+    userController := controllers.NewUserController(s)
+    accountController := controllers.NewAccountController(s)
+    trashController := controllers.NewTrashController(s)
+    categoryController := controllers.NewCategoryController(s)
+    externalController := controllers.NewExternalController(s)
+    mediaController := controllers.NewMediaController(s)
+    webController := controllers.NewWebController(s)
+
+    router := gin.New()
+    router.Use(middleware.RequestID(), middleware.Logger(), middleware.Recovery(), middleware.ErrorHandler(), middleware.Locale())
 
     // Serve static files
-    router.Static("/static", "./static")
-    router.Static("/images", "./images")
+    router.Static("/static", staticDir)
+    router.Static("/images", imagesDir)
+    router.GET("/media/*key", mediaController.ServeMedia)
 
     // Web routes (HTML pages)
-    router.GET("/", controllers.HomeHandler)
-    router.GET("/category/:category", controllers.CategoryHandler)
-    router.GET("/recipe/:id", controllers.RecipeHandler)
-    router.GET("/add-recipe", controllers.AddRecipeHandler)
+    router.GET("/", webController.HomeHandler)
+    router.GET("/category/:category", webController.CategoryHandler)
+    router.GET("/recipe/:id", webController.RecipeHandler)
+    router.GET("/add-recipe", webController.AddRecipeHandler)
     router.GET("/register", controllers.RegisterHandler)
-    router.GET("/featured", controllers.FeaturedHandler)
-    router.GET("/about", controllers.AboutHandler)
+    router.GET("/featured", webController.FeaturedHandler)
+    router.GET("/about", webController.AboutHandler)
+
+    // Moderation dashboard (HTTP Basic auth as a moderator or admin)
+    moderationPages := router.Group("/moderation", middleware.RequireModerator(s.Users))
+    {
+        moderationPages.GET("", moderationController.ModerationDashboardHandler)
+        moderationPages.POST("/:type/:id/:action", middleware.SameOrigin(), moderationController.ModerationActionHandler) // approve or reject from the dashboard
+    }
 
     // API version 1 group
     v1 := router.Group("/api/v1")
@@ -28,65 +61,95 @@ func SetupRoutes() *gin.Engine {
         // Recipe routes
         recipes := v1.Group("/recipes")
         {
-            recipes.GET("", controllers.GetRecipes)                           // Get all recipes with optional category filter
-            recipes.POST("", controllers.AddRecipe)                          // Add a new recipe
-            recipes.GET("/:id", controllers.GetRecipeByID)                   // Get recipe by ID
-            recipes.PUT("/:id", controllers.UpdateRecipe)                    // Update recipe
-            recipes.DELETE("/:id", controllers.DeleteRecipe)                 // Delete recipe
-            recipes.GET("/category/:category", controllers.GetRecipesByCategory) // Get recipes by category
-            recipes.GET("/top-rated", controllers.GetTopRatedRecipes)        // Get top rated recipes
+            recipes.GET("", recipeController.GetRecipes)                           // Get all recipes with optional category filter
+            recipes.POST("", recipeController.AddRecipe)                          // Add a new recipe
+            recipes.GET("/:id", recipeController.GetRecipeByID)                   // Get recipe by ID
+            recipes.PUT("/:id", recipeController.UpdateRecipe)                    // Update recipe
+            recipes.DELETE("/:id", recipeController.DeleteRecipe)                 // Move recipe to the trash
+            recipes.POST("/:id/restore", middleware.RequireUser(s.Users), trashController.RestoreRecipe) // Restore a deleted recipe (its author)
+            recipes.GET("/:id/photos", feedbackController.GetRecipePhotos)          // Get community photos of a recipe
+            recipes.POST("/:id/report", moderationController.ReportRecipe)            // Report a recipe to the moderators
+            recipes.GET("/:id/translations", translationController.GetRecipeTranslations)            // List a recipe's translations
+            recipes.PUT("/:id/translations/:locale", middleware.RequireUser(s.Users), translationController.PutRecipeTranslation)     // Add or replace a translation (its author)
+            recipes.DELETE("/:id/translations/:locale", middleware.RequireUser(s.Users), translationController.DeleteRecipeTranslation) // Remove a translation (its author)
+            recipes.GET("/category/:category", recipeController.GetRecipesByCategory) // Get recipes by category
+            recipes.GET("/top-rated", recipeController.GetTopRatedRecipes)        // Get top rated recipes
+            recipes.GET("/featured", recipeController.GetFeaturedRecipes)         // Get trending recipes
             recipes.GET("/search", controllers.GetSpoonacularRecipes)        // Search recipes using Spoonacular API
         }
 
         // Feedback routes
         feedback := v1.Group("/feedback")
         {
-            feedback.POST("", controllers.AddFeedback)                       // Add feedback for a recipe
-            feedback.GET("/recipe/:recipeId", controllers.GetRecipeFeedback) // Get all feedback for a recipe
-            feedback.PUT("/:id", controllers.UpdateFeedback)                 // Update feedback
-            feedback.DELETE("/:id", controllers.DeleteFeedback)              // Delete feedback
+            feedback.POST("", feedbackController.AddFeedback)                       // Add feedback for a recipe
+            feedback.GET("/recipe/:recipeId", feedbackController.GetRecipeFeedback) // Get all feedback for a recipe
+            feedback.PUT("/:id", feedbackController.UpdateFeedback)                 // Update feedback
+            feedback.DELETE("/:id", feedbackController.DeleteFeedback)              // Move feedback to the trash
+            feedback.POST("/:id/restore", middleware.RequireUser(s.Users), trashController.RestoreFeedback) // Restore deleted feedback (its author)
+            feedback.POST("/:id/replies", feedbackController.AddFeedbackReply)      // Reply to a review
+            feedback.POST("/:id/vote", feedbackController.VoteFeedback)             // Mark a review helpful or unhelpful
+            feedback.DELETE("/:id/vote", feedbackController.RetractFeedbackVote)    // Remove a helpfulness vote
+            feedback.POST("/:id/report", moderationController.ReportFeedback)         // Report a review to the moderators
+            feedback.POST("/:id/replies/:replyId/report", moderationController.ReportReply) // Report a reply to the moderators
+        }
+
+        // Moderation routes (HTTP Basic auth as a moderator or admin)
+        moderation := v1.Group("/moderation", middleware.RequireModerator(s.Users))
+        {
+            moderation.GET("/recipes", moderationController.GetRecipeQueue)           // List recipes by ?status= (default pending,flagged)
+            moderation.PUT("/recipes/:id", moderationController.ModerateRecipe)       // Approve or reject a recipe
+            moderation.GET("/feedback", moderationController.GetFeedbackQueue)        // List reviews by ?status=
+            moderation.PUT("/feedback/:id", moderationController.ModerateFeedback)    // Approve or reject a review
+            moderation.GET("/replies", moderationController.GetReplyQueue)            // List replies by ?status=
+            moderation.PUT("/replies/:id", moderationController.ModerateReply)        // Approve or reject a reply
         }
 
         // User routes
         users := v1.Group("/users")
         {
-            users.POST("/register", controllers.RegisterUser)               // Register new user
-            users.POST("/login", controllers.LoginUser)                     // User login
-            users.GET("", controllers.GetAllUsers)                          // Get all users (admin)
-            users.GET("/:id", controllers.GetUserProfile)                   // Get user profile
-            users.PUT("/:id", controllers.UpdateUserProfile)                // Update user profile
-            users.GET("/:id/recipes", controllers.GetUserRecipes)           // Get user's recipes
+            users.POST("/register", userController.RegisterUser)               // Register new user
+            users.POST("/login", userController.LoginUser)                     // User login
+            users.GET("", userController.GetAllUsers)                          // Get all users (admin)
+            users.GET("/:id", userController.GetUserProfile)                   // Get user profile
+            users.PUT("/:id", userController.UpdateUserProfile)                // Update user profile
+            users.GET("/:id/recipes", userController.GetUserRecipes)           // Get user's recipes
+
+            // Self-service account management; requires the account's own credentials
+            account := users.Group("/:id", middleware.RequireAccountOwner(s.Users))
+            {
+                account.POST("/deactivate", accountController.DeactivateAccount)  // Deactivate the account
+                account.POST("/reactivate", accountController.ReactivateAccount)  // Reactivate the account
+                account.DELETE("", accountController.DeleteAccount)               // Delete the account (?content=anonymize|remove)
+                account.GET("/export", accountController.ExportAccount)           // Download a ZIP of the user's data
+                account.GET("/trash", trashController.GetUserTrash)               // Get user's restorable deleted items
+            }
         }
 
 
 
-        // Category routes (for getting category information)
+        // Category routes
         categories := v1.Group("/categories")
         {
-            categories.GET("", func(c *gin.Context) {
-                c.JSON(200, gin.H{
-                    "categories": []gin.H{
-                        {"key": "plant_based_meals", "name": "Plant-Based Meals"},
-                        {"key": "kids_meals", "name": "Kids' Meals"},
-                        {"key": "light_meals", "name": "Light Meals (Weight Loss)"},
-                        {"key": "hearty_meals", "name": "Hearty Meals (Weight Gain)"},
-                        {"key": "meat_stews", "name": "Meat Stews"},
-                        {"key": "veggie_stews", "name": "Veggie Stews"},
-                        {"key": "seafood_stews", "name": "Seafood & Fish Stews"},
-                        {"key": "fusion_stews", "name": "Fusion Stews"},
-                        {"key": "soups", "name": "Soups"},
-                        {"key": "drinks", "name": "Drinks"},
-                        {"key": "pastries", "name": "Pastries"},
-                    },
-                })
-            })
+            categories.GET("", categoryController.GetCategories)       // List categories in display order
+            categories.GET("/:key", categoryController.GetCategory)    // Get a category
+
+            // Category management; requires admin credentials
+            admin := categories.Group("", middleware.RequireAdmin(s.Users))
+            {
+                admin.POST("", categoryController.CreateCategory)          // Create a category
+                admin.PUT("/:key", categoryController.UpdateCategory)      // Update a category
+                admin.DELETE("/:key", categoryController.DeleteCategory)   // Delete an unused category
+            }
         }
 
+        // Cuisine routes
+        v1.GET("/cuisines", categoryController.GetCuisines)                        // List cuisines recipes can be tagged with
+
         // External API integration routes
         external := v1.Group("/external")
         {
-            external.GET("/recipes/:category", controllers.SearchExternalRecipes)
-            external.GET("/api-mapping", controllers.GetAPIMappingInfo)
+            external.GET("/recipes/:category", externalController.SearchExternalRecipes)
+            external.GET("/api-mapping", externalController.GetAPIMappingInfo)
         }
     }
 
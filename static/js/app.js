@@ -50,6 +50,11 @@ function initializeForms() {
         feedbackForm.addEventListener('submit', handleFeedbackSubmission);
     }
 
+    // Reply forms in review threads
+    document.querySelectorAll('.reply-form').forEach(form => {
+        form.addEventListener('submit', handleReplySubmission);
+    });
+
     // User registration form
     const registerForm = document.getElementById('registerForm');
     if (registerForm) {
@@ -79,13 +84,70 @@ async function handleRecipeSubmission(event) {
 
         if (response.ok) {
             const result = await response.json();
+            // Recipes held by the content filter are not public until a moderator approves them
+            if (result.status !== 'approved') {
+                showSuccess('Recipe submitted! It will appear once a moderator has reviewed it.');
+                setTimeout(() => {
+                    window.location.href = '/';
+                }, 2500);
+                return;
+            }
             showSuccess('Recipe saved successfully!');
             setTimeout(() => {
                 window.location.href = `/recipe/${result.ID}`;
             }, 1500);
         } else {
             const error = await response.json();
-            showError(error.error || 'Failed to save recipe');
+            showError(formatApiError(error, 'Failed to save recipe'));
+        }
+    } catch (error) {
+        showError('Network error. Please try again.');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Show or hide the reply form belonging to a review or reply
+function toggleReplyForm(button) {
+    const container = button.closest('.reply') || button.closest('.feedback-item');
+    const form = container.querySelector('.reply-form');
+    form.style.display = form.style.display === 'none' ? 'block' : 'none';
+}
+
+// Handle Reply Submission
+async function handleReplySubmission(event) {
+    event.preventDefault();
+
+    const form = event.target;
+    const replyData = {
+        user_id: 1, // Default user for now
+        comment: new FormData(form).get('comment')
+    };
+    if (form.dataset.parentId) {
+        replyData.parent_id = parseInt(form.dataset.parentId);
+    }
+
+    try {
+        showLoading('Posting reply...');
+        const response = await fetch(`${API_BASE}/feedback/${form.dataset.feedbackId}/replies`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify(replyData)
+        });
+
+        if (response.ok) {
+            const result = await response.json();
+            showSuccess(result.status === 'approved'
+                ? 'Reply posted!'
+                : 'Reply submitted! It will appear once a moderator has reviewed it.');
+            setTimeout(() => {
+                location.reload();
+            }, 1000);
+        } else {
+            const error = await response.json();
+            showError(formatApiError(error, 'Failed to post reply'));
         }
     } catch (error) {
         showError('Network error. Please try again.');
@@ -94,37 +156,86 @@ async function handleRecipeSubmission(event) {
     }
 }
 
+// Mark a review helpful or unhelpful
+async function voteFeedback(feedbackId, helpful) {
+    try {
+        const response = await fetch(`${API_BASE}/feedback/${feedbackId}/vote`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ user_id: 1, helpful: helpful }) // Default user for now
+        });
+
+        if (response.ok) {
+            showSuccess('Thanks for your feedback!');
+            setTimeout(() => {
+                location.reload();
+            }, 1000);
+        } else {
+            const error = await response.json();
+            showError(formatApiError(error, 'Failed to record vote'));
+        }
+    } catch (error) {
+        showError('Network error. Please try again.');
+    }
+}
+
+// Report a recipe, review or reply to the moderators; type is the path of
+// the collection the item belongs to
+async function reportContent(type, id) {
+    const reason = prompt('Why are you reporting this? (spam, offensive, inappropriate or other)', 'spam');
+    if (reason === null) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`${API_BASE}/${type}/${id}/report`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ user_id: 1, reason: reason.trim().toLowerCase() }) // Default user for now
+        });
+
+        if (response.ok) {
+            showSuccess('Thanks, a moderator will take a look.');
+        } else {
+            const error = await response.json();
+            showError(formatApiError(error, 'Failed to send report'));
+        }
+    } catch (error) {
+        showError('Network error. Please try again.');
+    }
+}
+
 // Handle Feedback Submission
 async function handleFeedbackSubmission(event) {
     event.preventDefault();
     
+    // Sent as multipart so photos of the dish can be attached
     const formData = new FormData(event.target);
-    const feedbackData = {
-        recipe_id: parseInt(formData.get('recipe_id')),
-        user_id: 1, // Default user for now
-        comment: formData.get('comment'),
-        rating: parseInt(formData.get('rating'))
-    };
+    formData.set('user_id', 1); // Default user for now
 
     try {
         showLoading('Submitting feedback...');
         const response = await fetch(`${API_BASE}/feedback`, {
             method: 'POST',
-            headers: {
-                'Content-Type': 'application/json',
-            },
-            body: JSON.stringify(feedbackData)
+            body: formData
         });
 
         if (response.ok) {
-            showSuccess('Feedback submitted successfully!');
+            const result = await response.json();
+            showSuccess(result.status === 'approved'
+                ? 'Feedback submitted successfully!'
+                : 'Feedback submitted! It will appear once a moderator has reviewed it.');
             event.target.reset();
             setTimeout(() => {
                 location.reload();
             }, 1500);
         } else {
             const error = await response.json();
-            showError(error.error || 'Failed to submit feedback');
+            showError(formatApiError(error, 'Failed to submit feedback'));
         }
     } catch (error) {
         showError('Network error. Please try again.');
@@ -144,7 +255,8 @@ async function handleUserRegistration(event) {
         password: formData.get('password'),
         first_name: formData.get('first_name'),
         last_name: formData.get('last_name'),
-        bio: formData.get('bio')
+        bio: formData.get('bio'),
+        locale: formData.get('locale')
     };
 
     // Validate password confirmation
@@ -171,7 +283,7 @@ async function handleUserRegistration(event) {
             }, 1500);
         } else {
             const error = await response.json();
-            showError(error.error || 'Failed to create account');
+            showError(formatApiError(error, 'Failed to create account'));
         }
     } catch (error) {
         showError('Network error. Please try again.');
@@ -198,6 +310,13 @@ function hideLoading() {
     }
 }
 
+function formatApiError(error, fallback) {
+    if (Array.isArray(error.details) && error.details.length > 0) {
+        return error.details.map(f => `${f.field} ${f.message}`).join('; ');
+    }
+    return error.error || fallback;
+}
+
 function showSuccess(message) {
     showAlert(message, 'success');
 }
@@ -0,0 +1,92 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// LocalStore keeps blobs as files in a directory
+type LocalStore struct {
+    Dir string
+}
+
+// NewLocalStore returns a store writing under dir
+func NewLocalStore(dir string) *LocalStore {
+    return &LocalStore{Dir: dir}
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+    if err := ValidateKey(key); err != nil {
+        return "", err
+    }
+    return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes r to key, replacing any existing blob atomically
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+
+    // Write to a temporary file first so readers never see a partial blob
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(tmp, r); err != nil {
+        tmp.Close()
+        os.Remove(tmp.Name())
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        os.Remove(tmp.Name())
+        return err
+    }
+    if err := os.Chmod(tmp.Name(), 0644); err != nil {
+        os.Remove(tmp.Name())
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the blob at key
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    path, err := s.path(key)
+    if err != nil {
+        return nil, err
+    }
+    f, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, ErrNotFound
+    }
+    return f, err
+}
+
+// Delete removes the blob at key; deleting a missing key is not an error
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+// SignedURL returns the plain media URL: local media is public, so there is
+// no access to grant and ttl is ignored
+func (s *LocalStore) SignedURL(key string, ttl time.Duration) (string, error) {
+    if err := ValidateKey(key); err != nil {
+        return "", err
+    }
+    return MediaURL(key), nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "sync"
+    "time"
+)
+
+// MemoryStore keeps blobs in memory. It is intended for tests.
+type MemoryStore struct {
+    mu    sync.RWMutex
+    blobs map[string]memoryBlob
+}
+
+type memoryBlob struct {
+    data        []byte
+    contentType string
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{blobs: make(map[string]memoryBlob)}
+}
+
+// Put stores the contents of r under key
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+    if err := ValidateKey(key); err != nil {
+        return err
+    }
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.blobs[key] = memoryBlob{data: data, contentType: contentType}
+    return nil
+}
+
+// Get returns the blob at key
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    blob, ok := s.blobs[key]
+    if !ok {
+        return nil, ErrNotFound
+    }
+    return io.NopCloser(bytes.NewReader(blob.data)), nil
+}
+
+// Delete removes the blob at key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.blobs, key)
+    return nil
+}
+
+// SignedURL returns the plain media URL, like LocalStore
+func (s *MemoryStore) SignedURL(key string, ttl time.Duration) (string, error) {
+    if err := ValidateKey(key); err != nil {
+        return "", err
+    }
+    return MediaURL(key), nil
+}
+
+// Keys lists the stored keys
+func (s *MemoryStore) Keys() []string {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    keys := make([]string, 0, len(s.blobs))
+    for key := range s.blobs {
+        keys = append(keys, key)
+    }
+    return keys
+}
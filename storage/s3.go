@@ -0,0 +1,249 @@
+package storage
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// S3Config configures an S3-compatible store (AWS S3, MinIO, R2, ...)
+type S3Config struct {
+    Endpoint  string `json:"endpoint"` // e.g. http://localhost:9000; defaults to AWS for Region
+    Region    string `json:"region"`
+    Bucket    string `json:"bucket"`
+    AccessKey string `json:"access_key"`
+    SecretKey string `json:"secret_key"`
+    PathStyle bool   `json:"path_style"` // Address the bucket in the path rather than the host name (needed by MinIO)
+}
+
+// S3Store keeps blobs in an S3-compatible bucket, signing requests with AWS
+// Signature Version 4
+type S3Store struct {
+    cfg      S3Config
+    endpoint *url.URL
+    Client   *http.Client
+    Now      func() time.Time // Clock used for signing; overridable in tests
+}
+
+// NewS3Store validates cfg and returns a store for its bucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+    if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+        return nil, errors.New("S3 storage needs a bucket, access key and secret key")
+    }
+    if cfg.Region == "" {
+        cfg.Region = "us-east-1"
+    }
+    if cfg.Endpoint == "" {
+        cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+    }
+    endpoint, err := url.Parse(cfg.Endpoint)
+    if err != nil || endpoint.Host == "" {
+        return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+    }
+    return &S3Store{cfg: cfg, endpoint: endpoint, Client: http.DefaultClient, Now: time.Now}, nil
+}
+
+// Put uploads the contents of r to key
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+    body, err := io.ReadAll(r)
+    if err != nil {
+        return err
+    }
+    resp, err := s.do(ctx, http.MethodPut, key, body, contentType)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return s3Error(resp)
+    }
+    return nil
+}
+
+// Get downloads the blob at key
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+    if err != nil {
+        return nil, err
+    }
+    switch resp.StatusCode {
+    case http.StatusOK:
+        return resp.Body, nil
+    case http.StatusNotFound:
+        resp.Body.Close()
+        return nil, ErrNotFound
+    default:
+        defer resp.Body.Close()
+        return nil, s3Error(resp)
+    }
+}
+
+// Delete removes the blob at key; deleting a missing key is not an error
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+    resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+        return s3Error(resp)
+    }
+    return nil
+}
+
+// SignedURL returns a presigned GET URL for key, valid for ttl (at most 7 days)
+func (s *S3Store) SignedURL(key string, ttl time.Duration) (string, error) {
+    if err := ValidateKey(key); err != nil {
+        return "", err
+    }
+    if ttl <= 0 || ttl > 7*24*time.Hour {
+        return "", errors.New("signed URL lifetime must be between 1 second and 7 days")
+    }
+
+    now := s.Now().UTC()
+    host, uri := s.location(key)
+    query := url.Values{
+        "X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+        "X-Amz-Credential":    {s.cfg.AccessKey + "/" + s.scope(now)},
+        "X-Amz-Date":          {now.Format("20060102T150405Z")},
+        "X-Amz-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+        "X-Amz-SignedHeaders": {"host"},
+    }
+    canonicalQuery := canonicalQueryString(query)
+    canonical := strings.Join([]string{
+        http.MethodGet, uri, canonicalQuery, "host:" + host + "\n", "host", "UNSIGNED-PAYLOAD",
+    }, "\n")
+    signature := s.signature(now, canonical)
+    return s.endpoint.Scheme + "://" + host + uri + "?" + canonicalQuery + "&X-Amz-Signature=" + signature, nil
+}
+
+// do sends a signed request for key
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+    if err := ValidateKey(key); err != nil {
+        return nil, err
+    }
+
+    now := s.Now().UTC()
+    host, uri := s.location(key)
+    payloadHash := sha256Hex(body)
+    amzDate := now.Format("20060102T150405Z")
+
+    headers := map[string]string{
+        "host":                 host,
+        "x-amz-content-sha256": payloadHash,
+        "x-amz-date":           amzDate,
+    }
+    if contentType != "" {
+        headers["content-type"] = contentType
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var canonicalHeaders strings.Builder
+    for _, name := range names {
+        canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    canonical := strings.Join([]string{method, uri, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+    authorization := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        s.cfg.AccessKey, s.scope(now), signedHeaders, s.signature(now, canonical))
+
+    req, err := http.NewRequestWithContext(ctx, method, s.endpoint.Scheme+"://"+host+uri, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    for _, name := range names {
+        if name != "host" {
+            req.Header.Set(name, headers[name])
+        }
+    }
+    req.Header.Set("Authorization", authorization)
+    return s.Client.Do(req)
+}
+
+// location returns the host and escaped path addressing key
+func (s *S3Store) location(key string) (string, string) {
+    if s.cfg.PathStyle {
+        return s.endpoint.Host, "/" + uriEscape(s.cfg.Bucket, false) + "/" + uriEscape(key, false)
+    }
+    return s.cfg.Bucket + "." + s.endpoint.Host, "/" + uriEscape(key, false)
+}
+
+func (s *S3Store) scope(t time.Time) string {
+    return t.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with the key derived for t's date
+func (s *S3Store) signature(t time.Time, canonical string) string {
+    stringToSign := strings.Join([]string{
+        "AWS4-HMAC-SHA256", t.Format("20060102T150405Z"), s.scope(t), sha256Hex([]byte(canonical)),
+    }, "\n")
+    key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), t.Format("20060102"))
+    for _, part := range []string{s.cfg.Region, "s3", "aws4_request"} {
+        key = hmacSHA256(key, part)
+    }
+    return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQueryString encodes query parameters sorted by name as SigV4 requires
+func canonicalQueryString(q url.Values) string {
+    names := make([]string, 0, len(q))
+    for name := range q {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    parts := make([]string, 0, len(names))
+    for _, name := range names {
+        for _, value := range q[name] {
+            parts = append(parts, uriEscape(name, true)+"="+uriEscape(value, true))
+        }
+    }
+    return strings.Join(parts, "&")
+}
+
+// uriEscape percent-encodes everything except unreserved characters, and
+// slashes too when encodeSlash is set
+func uriEscape(s string, encodeSlash bool) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+            c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+            b.WriteByte(c)
+            continue
+        }
+        fmt.Fprintf(&b, "%%%02X", c)
+    }
+    return b.String()
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    m := hmac.New(sha256.New, key)
+    m.Write([]byte(data))
+    return m.Sum(nil)
+}
+
+// s3Error describes an unexpected S3 response
+func s3Error(resp *http.Response) error {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    return fmt.Errorf("S3 request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "io"
+    "path"
+    "strings"
+    "time"
+)
+
+// MediaPrefix is the public URL path stored media is served under,
+// independent of the backend holding the bytes
+const MediaPrefix = "/media/"
+
+// ErrNotFound is returned when a key does not exist in the store
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that could escape the store's namespace
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore stores uploaded media by key
+type BlobStore interface {
+    Put(ctx context.Context, key string, r io.Reader, contentType string) error
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    Delete(ctx context.Context, key string) error
+    // SignedURL returns a URL granting read access to key until ttl elapses
+    SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// MediaURL returns the stable public URL for a key
+func MediaURL(key string) string {
+    return MediaPrefix + key
+}
+
+// KeyFromURL returns the key of a media URL, or false if url is not one
+func KeyFromURL(url string) (string, bool) {
+    key := strings.TrimPrefix(url, MediaPrefix)
+    if key == url || ValidateKey(key) != nil {
+        return "", false
+    }
+    return key, true
+}
+
+// ValidateKey rejects empty keys and keys containing path traversal
+func ValidateKey(key string) error {
+    if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+        return ErrInvalidKey
+    }
+    return nil
+}
+
+// Remote reports whether clients should fetch blobs directly from the backend
+// through signed URLs rather than having the application stream them
+func Remote(store BlobStore) bool {
+    _, ok := store.(*S3Store)
+    return ok
+}
@@ -10,11 +10,11 @@ echo "1. Testing server connection..."
 if curl -s http://localhost:8080/health > /dev/null 2>&1; then
     echo "✅ Server is running on port 8080"
 else
-    echo "❌ Server is not running. Please start with 'go run main.go'"
+    echo "❌ Server is not running. Please start with 'go run .'"
     echo ""
     echo "To start the server:"
     echo "1. Fix Go module issues: GOPROXY=direct GOSUMDB=off go mod tidy"
-    echo "2. Run the server: GOPROXY=direct GOSUMDB=off go run main.go"
+    echo "2. Run the server: GOPROXY=direct GOSUMDB=off go run ."
     echo "3. Then run this test again"
     exit 1
 fi